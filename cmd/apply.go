@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/elliotcubit/renamer/pkg/file"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply plan.json",
+	Short: "Perform the renames in a plan written by the plan command.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(args[0])
+		if err != nil {
+			fmt.Printf("apply: %v\n", err)
+			os.Exit(1)
+		}
+		plan, err := file.ReadPlan(f)
+		f.Close()
+		if err != nil {
+			fmt.Printf("apply: bad plan: %v\n", err)
+			os.Exit(1)
+		}
+
+		if cmd.Flag(DryRunFlagName).Changed {
+			err = plan.Verify()
+			if err == nil {
+				plan.Print(os.Stdout)
+			}
+		} else {
+			err = plan.Apply(file.NewJournal(journalPath(cmd), plan.Pattern, plan.Template))
+		}
+		if err != nil {
+			fmt.Printf("apply: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
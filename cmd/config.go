@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/elliotcubit/renamer/pkg/config"
+	"github.com/elliotcubit/renamer/pkg/file"
+	"github.com/spf13/cobra"
+)
+
+const (
+	ConfigFlagName   = "config"
+	ProfileFlagName  = "profile"
+	PatternsFlagName = "patterns"
+)
+
+func init() {
+	rootCmd.PersistentFlags().String(ConfigFlagName, "", "Configuration file to read profiles from (default $XDG_CONFIG_HOME/renamer/config.json)")
+	rootCmd.PersistentFlags().String(ProfileFlagName, "", "Profile of the configuration file to use (default the file's default_profile)")
+
+	rootCmd.PersistentFlags().String(PatternsFlagName, "", "Pattern library file, or directory of them, to infer patterns with besides the included ones (default $XDG_CONFIG_HOME/renamer/patterns)")
+
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		p, err := profileFromFlags(cmd)
+		if err != nil {
+			// Undoing or applying what was already planned mustn't depend
+			// on the rest of the configuration being valid
+			if !undoesOrApplies(cmd) {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%v; ignoring the configuration\n", err)
+		}
+		profile = p
+		applyProfile(cmd, profile)
+		if usesPatterns(cmd) {
+			loadPatterns(cmd)
+		}
+	}
+}
+
+// usesPatterns reports whether cmd matches file names, and so needs the
+// pattern libraries.
+func usesPatterns(cmd *cobra.Command) bool {
+	return cmd == rootCmd || cmd == planCmd || cmd == patternsCmd || cmd.Parent() == patternsCmd
+}
+
+// undoesOrApplies reports whether cmd only undoes or applies earlier runs,
+// and so only needs the journal from the configuration.
+func undoesOrApplies(cmd *cobra.Command) bool {
+	return cmd == undoCmd || cmd == applyCmd
+}
+
+// profile is the configuration profile selected by the --profile flag.
+var profile config.Profile
+
+// profileFromFlags loads the configuration file given by the --config flag,
+// or the default one if it exists, and returns the profile selected by the
+// --profile flag.
+func profileFromFlags(cmd *cobra.Command) (config.Profile, error) {
+	path := cmd.Flag(ConfigFlagName).Value.String()
+	name := cmd.Flag(ProfileFlagName).Value.String()
+
+	explicit := path != ""
+	if !explicit {
+		var err error
+		path, err = config.DefaultPath()
+		if err != nil {
+			return config.Profile{}, fmt.Errorf("config: %w", err)
+		}
+	}
+
+	cfg, err := config.Load(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		if name != "" {
+			return config.Profile{}, fmt.Errorf("--%s: no configuration file at %q", ProfileFlagName, path)
+		}
+		return config.Profile{}, nil
+	}
+	if err != nil {
+		return config.Profile{}, fmt.Errorf("config: %w", err)
+	}
+
+	p, err := cfg.Profile(name)
+	if err != nil {
+		return config.Profile{}, fmt.Errorf("config: %w", err)
+	}
+	return p, nil
+}
+
+// applyProfile sets the flags that p has a value for, unless they were
+// given on the command line, which overrides the configuration.
+func applyProfile(cmd *cobra.Command, p config.Profile) {
+	values := map[string]string{
+		KindFlagName:      p.Kind,
+		OutputFlagName:    p.Template,
+		DestFlagName:      p.Dest,
+		ConflictFlagName:  p.OnConflict,
+		SanitizeFlagName:  p.Sanitize,
+		MapFlagName:       p.EpisodeMap,
+		JournalFlagName:   p.Journal,
+		NormalizeFlagName: strings.Join(p.Normalize, ","),
+	}
+	if p.MatchPath {
+		values[MatchPathFlagName] = "true"
+	}
+	for k, v := range values {
+		flag := cmd.Flag(k)
+		if v == "" || flag == nil || flag.Changed {
+			continue
+		}
+		if err := flag.Value.Set(v); err != nil {
+			fmt.Printf("config: %s: %v\n", k, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// loadPatterns loads the pattern library given by the --patterns flag, or
+// the default one if it exists.
+func loadPatterns(cmd *cobra.Command) {
+	path := cmd.Flag(PatternsFlagName).Value.String()
+	explicit := path != ""
+	if !explicit {
+		var err error
+		path, err = config.DefaultPatternsPath()
+		if err != nil {
+			fmt.Printf("patterns: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	err := file.LoadPatterns(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return
+	}
+	if err != nil {
+		fmt.Printf("patterns: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/elliotcubit/renamer/pkg/file"
+	"github.com/elliotcubit/renamer/pkg/regexps"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	patternsCmd.AddCommand(patternsListCmd, patternsTestCmd, patternsExplainCmd)
+	rootCmd.AddCommand(patternsCmd)
+}
+
+var patternsCmd = &cobra.Command{
+	Use:   "patterns",
+	Short: "List, test and explain the patterns that file names are matched with.",
+}
+
+var patternsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profile, library and included patterns, in the order they are tried.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		defaults := defaultsFromFlags(cmd)
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tNAME\tPRIORITY\tSOURCE\tPATTERN")
+		for _, kind := range kindsFromFlags(cmd) {
+			switch kind {
+			case file.KindMovie:
+				listPatterns(w, kind, patternsFor[file.Movie](kind, defaults))
+			case file.KindDaily:
+				listPatterns(w, kind, patternsFor[file.Daily](kind, defaults))
+			case file.KindAnime:
+				listPatterns(w, kind, patternsFor[file.Absolute](kind, defaults))
+			default:
+				listPatterns(w, kind, patternsFor[file.Match](kind, defaults))
+			}
+		}
+		w.Flush()
+	},
+}
+
+var patternsTestCmd = &cobra.Command{
+	Use:   "test pattern filename...",
+	Short: "Show what a pattern, or the pattern with that name, captures from file names.",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		kind := file.KindTV
+		if v := cmd.Flag(KindFlagName).Value.String(); v != "" {
+			kind = kindFromFlags(cmd, nil, "")
+		}
+		defaults := defaultsFromFlags(cmd)
+		matchPath := matchPathFromFlags(cmd)
+
+		switch kind {
+		case file.KindMovie:
+			testPattern(patternFromArg[file.Movie](kind, args[0], defaults), args[1:], matchPath)
+		case file.KindDaily:
+			testPattern(patternFromArg[file.Daily](kind, args[0], defaults), args[1:], matchPath)
+		case file.KindAnime:
+			testPattern(patternFromArg[file.Absolute](kind, args[0], defaults), args[1:], matchPath)
+		default:
+			testPattern(patternFromArg[file.Match](kind, args[0], defaults), args[1:], matchPath)
+		}
+	},
+}
+
+var patternsExplainCmd = &cobra.Command{
+	Use:   "explain filename",
+	Short: "Show which patterns match a file name, and why the others don't.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		defaults := defaultsFromFlags(cmd)
+		matchPath := matchPathFromFlags(cmd)
+		for _, kind := range kindsFromFlags(cmd) {
+			fmt.Printf("%s:\n", kind)
+			switch kind {
+			case file.KindMovie:
+				explainPatterns(patternsFor[file.Movie](kind, defaults), args[0], matchPath)
+			case file.KindDaily:
+				explainPatterns(patternsFor[file.Daily](kind, defaults), args[0], matchPath)
+			case file.KindAnime:
+				explainPatterns(patternsFor[file.Absolute](kind, defaults), args[0], matchPath)
+			default:
+				explainPatterns(patternsFor[file.Match](kind, defaults), args[0], matchPath)
+			}
+		}
+	},
+}
+
+// kindsFromFlags returns the kind given by the --kind flag, or every kind.
+func kindsFromFlags(cmd *cobra.Command) []file.Kind {
+	if cmd.Flag(KindFlagName).Value.String() != "" {
+		return []file.Kind{kindFromFlags(cmd, nil, "")}
+	}
+	return file.Kinds
+}
+
+// patternsFor returns the patterns of kind in the order they are tried:
+// those of the profile, if it is for kind, then the library and included
+// patterns.
+func patternsFor[T any](kind file.Kind, defaults map[string]string) []*file.Pattern[T] {
+	var patterns []*file.Pattern[T]
+	if profile.Kind == string(kind) || (profile.Kind == "" && kind == file.KindTV) {
+		patterns = profilePatterns[T](defaults)
+	}
+	return append(patterns, file.PatternsFor[T]()...)
+}
+
+// patternFromArg returns the pattern of kind called name, or compiles name
+// as a pattern if there is none.
+func patternFromArg[T any](kind file.Kind, name string, defaults map[string]string) *file.Pattern[T] {
+	for _, v := range patternsFor[T](kind, defaults) {
+		if v.Name == name {
+			return v
+		}
+	}
+	pattern, err := regexps.CompileWithDefaults[T](name, defaults)
+	if err != nil {
+		fmt.Printf("bad pattern: %v\n", err)
+		os.Exit(1)
+	}
+	return &file.Pattern[T]{Regexp: pattern, Name: "--"}
+}
+
+func listPatterns[T any](w io.Writer, kind file.Kind, patterns []*file.Pattern[T]) {
+	for _, v := range patterns {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", kind, v.Name, v.Priority, v.Source, v.Regexp)
+	}
+}
+
+// testPattern matches the name of each file against pattern, or its path
+// with matchPath, with the directories in its path as the context for
+// defaults.
+func testPattern[T any](pattern *file.Pattern[T], files []string, matchPath bool) {
+	for _, v := range files {
+		fmt.Printf("%q:\n", v)
+		name := matchSubject(v, matchPath)
+		groups := pattern.Groups(name)
+		if groups == nil {
+			fmt.Println("  no match")
+			continue
+		}
+		printGroups(groups, pattern.Participated(name))
+		printMatch(pattern.ParseStringContext(name, file.PathContext(v)))
+	}
+}
+
+func explainPatterns[T any](patterns []*file.Pattern[T], path string, matchPath bool) {
+	name := matchSubject(path, matchPath)
+	for _, v := range patterns {
+		groups := v.Groups(name)
+		if groups == nil {
+			fmt.Printf("  %s: no match\n", v.Name)
+			continue
+		}
+		fmt.Printf("  %s:\n", v.Name)
+		printGroups(groups, v.Participated(name))
+		printMatch(v.ParseStringContext(name, file.PathContext(path)))
+	}
+}
+
+// matchSubject returns what patterns are matched against for the file at
+// path: its name, or with matchPath, the path with "/" separators.
+func matchSubject(path string, matchPath bool) string {
+	if matchPath {
+		return filepath.ToSlash(path)
+	}
+	return filepath.Base(path)
+}
+
+// printGroups prints the named groups of a match, sorted by name, marking
+// those that didn't take part in it and so are given their default.
+func printGroups(groups map[string]string, participated map[string]bool) {
+	names := make([]string, 0, len(groups))
+	for k := range groups {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, v := range names {
+		if !participated[v] {
+			fmt.Printf("    %s not matched\n", v)
+			continue
+		}
+		fmt.Printf("    %s = %q\n", v, groups[v])
+	}
+}
+
+func printMatch[T any](match *T, err error) {
+	if err != nil {
+		fmt.Printf("    => %v\n", err)
+		return
+	}
+	b, err := json.Marshal(match)
+	if err != nil {
+		fmt.Printf("    => %+v\n", *match)
+		return
+	}
+	fmt.Printf("    => %s\n", b)
+}
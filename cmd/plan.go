@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/elliotcubit/renamer/pkg/file"
+	"github.com/spf13/cobra"
+)
+
+const (
+	OutFlagName = "out"
+)
+
+func init() {
+	planCmd.Flags().String(OutFlagName, "-", "File to write the plan to, or - for stdout")
+
+	rootCmd.AddCommand(planCmd)
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Write the renames that would be done as JSON, to be applied later.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := cmd.Flag(DirFlagName).Value.String()
+		fs := os.DirFS(dir)
+
+		var plan *file.Plan
+		switch kindFromFlags(cmd, fs, dir) {
+		case file.KindMovie:
+			plan = newPlan[file.Movie](cmd, fs, dir, patternFromFlags(cmd, fs, dir, file.InferMoviePattern))
+		case file.KindDaily:
+			plan = newPlan[file.Daily](cmd, fs, dir, patternFromFlags(cmd, fs, dir, file.InferDailyPattern))
+		case file.KindAnime:
+			plan = newPlan[file.Match](cmd, fs, dir, absoluteFinderFromFlags(cmd, fs, dir))
+		default:
+			plan = newPlan[file.Match](cmd, fs, dir, patternFromFlags(cmd, fs, dir, file.InferPattern))
+		}
+
+		out := os.Stdout
+		var err error
+		if v := cmd.Flag(OutFlagName).Value.String(); v != "-" {
+			out, err = os.Create(v)
+			if err != nil {
+				fmt.Printf("plan: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		err = plan.Write(out)
+		if err == nil {
+			err = out.Close()
+		}
+		if err != nil {
+			fmt.Printf("plan: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func newPlan[T file.Media](cmd *cobra.Command, fsys fs.FS, dir string, pattern file.Finder[T]) *file.Plan {
+	plan, err := file.NewPlan(
+		fsys,
+		dir,
+		pattern,
+		optionsFromFlags(cmd, file.KindOf[T]()),
+	)
+	if err != nil {
+		fmt.Printf("plan: %v\n", err)
+		os.Exit(1)
+	}
+	return plan
+}
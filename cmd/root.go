@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"strings"
 
 	"github.com/elliotcubit/renamer/pkg/file"
 	"github.com/elliotcubit/renamer/pkg/regexps"
@@ -10,18 +13,38 @@ import (
 )
 
 const (
-	PatternFlagName  = "pattern"
-	TemplateFlagName = "template"
-	DirFlagName      = "dir"
-	DryRunFlagName   = "dry-run"
-	OutputFlagName   = "output-template"
+	PatternFlagName   = "pattern"
+	TemplateFlagName  = "template"
+	DirFlagName       = "dir"
+	DryRunFlagName    = "dry-run"
+	OutputFlagName    = "output-template"
+	JournalFlagName   = "journal"
+	ConflictFlagName  = "on-conflict"
+	KindFlagName      = "kind"
+	MapFlagName       = "episode-map"
+	DestFlagName      = "dest"
+	SanitizeFlagName  = "sanitize"
+	PerFileFlagName   = "per-file"
+	UseFlagName       = "use-pattern"
+	MatchPathFlagName = "match-path"
+	NormalizeFlagName = "normalize"
 )
 
 func init() {
 	rootCmd.PersistentFlags().StringP(PatternFlagName, "p", "", "Pattern of files to pick up")
 	rootCmd.PersistentFlags().StringP(DirFlagName, "d", ".", "Directory to check")
 	rootCmd.PersistentFlags().Bool(DryRunFlagName, false, "Do not modify any files; instead, print what would be done")
-	rootCmd.PersistentFlags().StringP(OutputFlagName, "o", "{{ .ShowName }} s{{ .Season }}e{{ .Episode }} - {{ .Title }}", "The template to rename files to, not including any file extension")
+	rootCmd.PersistentFlags().StringP(OutputFlagName, "o", "", "The template to rename files to, not including any file extension (default depends on --kind)")
+	rootCmd.PersistentFlags().String(UseFlagName, "", "Name of the included, library or profile pattern to use, instead of inferring one")
+	rootCmd.PersistentFlags().Bool(PerFileFlagName, false, "Match each file against the patterns on its own, instead of requiring one pattern to match every file")
+	rootCmd.PersistentFlags().Bool(MatchPathFlagName, false, "Match patterns against the path of each file relative to --dir, with / separators, instead of its name")
+	rootCmd.PersistentFlags().String(DestFlagName, "", "Library root to move files into, instead of renaming them in place")
+	rootCmd.PersistentFlags().String(KindFlagName, "", "The kind of media to rename, tv, anime, daily or movie (default detected from the files, or tv with --pattern)")
+	rootCmd.PersistentFlags().String(MapFlagName, "", "JSON file mapping absolute episode numbers to seasons, for --kind anime")
+	rootCmd.PersistentFlags().String(NormalizeFlagName, "", "Steps to clean up captured names and titles with, separated by commas: tags, undot, release, apostrophes and title, or all")
+	rootCmd.PersistentFlags().String(SanitizeFlagName, file.ProfilePOSIX.Name, "The filesystem to make names safe for: posix, windows, smb or macos")
+	rootCmd.PersistentFlags().String(ConflictFlagName, string(file.ConflictAbort), "What to do when a target is already taken: abort, skip, suffix or overwrite")
+	rootCmd.PersistentFlags().String(JournalFlagName, "", "Journal file to record renames in (default $XDG_STATE_HOME/renamer/journal.jsonl)")
 
 	for k, v := range defaultArgs {
 		rootCmd.PersistentFlags().String(k, "", v)
@@ -29,57 +52,303 @@ func init() {
 }
 
 var defaultArgs = map[string]string{
-	"name":   "The name of the show",
-	"season": "The season the episode is in",
+	"name":   "The name of the show, or a template of it such as {{ .grandparent }}",
+	"season": "The season the episode is in, or a template of it such as {{ number .parent }}",
+	"year":   "The year the movie was released",
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "renamer",
 	Short: "renamer renames files to a standard format.",
 	Run: func(cmd *cobra.Command, args []string) {
-		defaults := make(map[string]string, 0)
-		for k := range defaultArgs {
-			if v := cmd.Flag(k).Value.String(); v != "" {
-				defaults[k] = v
-			}
+		dir := cmd.Flag(DirFlagName).Value.String()
+		fs := os.DirFS(dir)
+
+		switch kindFromFlags(cmd, fs, dir) {
+		case file.KindMovie:
+			renameAll[file.Movie](cmd, fs, dir, patternFromFlags(cmd, fs, dir, file.InferMoviePattern))
+		case file.KindDaily:
+			renameAll[file.Daily](cmd, fs, dir, patternFromFlags(cmd, fs, dir, file.InferDailyPattern))
+		case file.KindAnime:
+			renameAll[file.Match](cmd, fs, dir, absoluteFinderFromFlags(cmd, fs, dir))
+		default:
+			renameAll[file.Match](cmd, fs, dir, patternFromFlags(cmd, fs, dir, file.InferPattern))
 		}
+	},
+}
 
-		var pattern *regexps.Regexp[file.Match]
-		var err error
-		rawPattern := cmd.Flag(PatternFlagName).Value.String()
+type inferFunc[T any] func(fs.FS, string) (*regexps.Regexp[T], error)
 
-		dir := cmd.Flag(DirFlagName).Value.String()
-		fs := os.DirFS(dir)
+func renameAll[T file.Media](cmd *cobra.Command, fsys fs.FS, dir string, pattern file.Finder[T]) {
+	opts := optionsFromFlags(cmd, file.KindOf[T]())
+	dry := cmd.Flag(DryRunFlagName).Changed
 
-		if rawPattern != "" {
-			pattern, err = regexps.CompileWithDefaults[file.Match](cmd.Flag(PatternFlagName).Value.String(), defaults)
-			if err != nil {
-				fmt.Printf("bad pattern: %v\n", err)
-				os.Exit(1)
+	var journal *file.Journal
+	if !dry {
+		journal = file.NewJournal(journalPath(cmd), pattern.String(), opts.Template)
+	}
+
+	err := file.RenameAllFiles(
+		fsys,
+		dir,
+		pattern,
+		dry,
+		opts,
+		journal,
+	)
+	if err != nil {
+		fmt.Printf("rename: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// kindFromFlags returns the kind given by the --kind flag. Without it, the
+// kind is TV if a pattern was given, by the flag or the profile, and
+// otherwise inferred from the files. With --per-file, files that don't all
+// match one kind are taken to be TV, and with --match-path, the kind isn't
+// inferred, as the patterns of each kind match file names.
+func kindFromFlags(cmd *cobra.Command, fsys fs.FS, dir string) file.Kind {
+	if v := cmd.Flag(KindFlagName).Value.String(); v != "" {
+		kind, err := file.ParseKind(v)
+		if err != nil {
+			fmt.Printf("--%s: %v\n", KindFlagName, err)
+			os.Exit(1)
+		}
+		return kind
+	}
+
+	if cmd.Flag(PatternFlagName).Value.String() != "" || len(profile.Patterns) > 0 || matchPathFromFlags(cmd) {
+		return file.KindTV
+	}
+
+	kind, err := file.InferKind(fsys, dir)
+	if err != nil && cmd.Flag(PerFileFlagName).Changed {
+		return file.KindTV
+	}
+	if err != nil {
+		fmt.Printf("no --pattern; %v\n", err)
+		os.Exit(1)
+	}
+	return kind
+}
+
+// patternFromFlags compiles the --pattern flag with the default arguments.
+// If it was not provided, the patterns of the profile are tried, and without
+// those a pattern is inferred from the files in dir. With --per-file, each
+// file is matched against those patterns on its own instead. With
+// --match-path, there is nothing to infer from, and the included and library
+// patterns can't be used, as they match file names. Default arguments given as flags override those
+// of the profile.
+func patternFromFlags[T any](cmd *cobra.Command, fsys fs.FS, dir string, infer inferFunc[T]) file.Finder[T] {
+	defaults := defaultsFromFlags(cmd)
+
+	rawPattern := cmd.Flag(PatternFlagName).Value.String()
+	if rawPattern != "" {
+		pattern, err := regexps.CompileWithDefaults[T](rawPattern, defaults)
+		if err != nil {
+			fmt.Printf("bad pattern: %v\n", err)
+			os.Exit(1)
+		}
+		return pattern
+	}
+
+	perFile := cmd.Flag(PerFileFlagName).Changed
+
+	patterns := profilePatterns[T](defaults)
+
+	matchPath := matchPathFromFlags(cmd)
+
+	if name := cmd.Flag(UseFlagName).Value.String(); name != "" {
+		for _, v := range patterns {
+			if v.Name == name {
+				return v
 			}
-		} else {
-			pattern, err = file.InferPattern(
-				fs,
-				dir,
-			)
-			if err != nil {
-				fmt.Printf("no --pattern; %v\n", err)
+		}
+		for _, v := range file.PatternsFor[T]() {
+			if v.Name != name {
+				continue
+			}
+			// The included and library patterns match file names, not paths
+			if matchPath {
+				fmt.Printf("--%s: pattern %q matches file names, so it can't be used with --%s\n", UseFlagName, name, MatchPathFlagName)
 				os.Exit(1)
 			}
+			return v
+		}
+		fmt.Printf("--%s: no pattern %q for this kind\n", UseFlagName, name)
+		os.Exit(1)
+	}
+
+	if len(patterns) > 0 {
+		if perFile {
+			return &file.PerFile[T]{Patterns: patterns}
+		}
+		choose := file.ChoosePattern[T]
+		if matchPath {
+			choose = file.ChoosePathPattern[T]
 		}
+		pattern, err := choose(fsys, dir, patterns)
+		if err != nil {
+			exitInferError(fmt.Errorf("profile patterns: %w", err))
+		}
+		return pattern
+	}
+
+	// The included and library patterns match file names, not paths
+	if matchPath {
+		fmt.Printf("--%s needs a --%s or patterns in the profile\n", MatchPathFlagName, PatternFlagName)
+		os.Exit(1)
+	}
 
-		err = file.RenameAllFiles(
-			fs,
-			dir,
-			pattern,
-			cmd.Flag(DryRunFlagName).Changed,
-			cmd.Flag(OutputFlagName).Value.String(),
-		)
+	if perFile {
+		return &file.PerFile[T]{Patterns: file.PatternsFor[T]()}
+	}
+
+	pattern, err := infer(
+		fsys,
+		dir,
+	)
+	if err != nil {
+		exitInferError(err)
+	}
+	return pattern
+}
+
+// profilePatterns compiles the patterns of the profile with defaults.
+func profilePatterns[T any](defaults map[string]string) []*file.Pattern[T] {
+	patterns := make([]*file.Pattern[T], len(profile.Patterns))
+	for i, v := range profile.Patterns {
+		pattern, err := regexps.CompileWithDefaults[T](v, defaults)
 		if err != nil {
-			fmt.Printf("rename: %v", err)
+			fmt.Printf("bad pattern in profile: %v\n", err)
 			os.Exit(1)
 		}
-	},
+		patterns[i] = &file.Pattern[T]{Regexp: pattern, Name: fmt.Sprintf("profile #%d", i+1), Source: "profile"}
+	}
+	return patterns
+}
+
+// defaultsFromFlags returns the default arguments of the profile, overridden
+// by those given as flags.
+func defaultsFromFlags(cmd *cobra.Command) map[string]string {
+	defaults := make(map[string]string, 0)
+	for k, v := range profile.Defaults {
+		defaults[k] = v
+	}
+	for k := range defaultArgs {
+		if v := cmd.Flag(k).Value.String(); v != "" {
+			defaults[k] = v
+		}
+	}
+	return defaults
+}
+
+// exitInferError reports that a pattern could not be inferred, and how to
+// choose one if several matched.
+func exitInferError(err error) {
+	fmt.Printf("no --pattern; %v\n", err)
+	var ambiguous *file.AmbiguousPatternError
+	if errors.As(err, &ambiguous) {
+		fmt.Printf("Choose one with --%s, or give a --%s\n", UseFlagName, PatternFlagName)
+	}
+	os.Exit(1)
+}
+
+// absoluteFinderFromFlags pairs the pattern for absolute episode numbers with
+// the episode map given by the --episode-map flag.
+func absoluteFinderFromFlags(cmd *cobra.Command, fsys fs.FS, dir string) *file.AbsoluteFinder {
+	path := cmd.Flag(MapFlagName).Value.String()
+	if path == "" {
+		fmt.Printf("--%s is required for anime\n", MapFlagName)
+		os.Exit(1)
+	}
+	episodeMap, err := file.LoadEpisodeMap(path)
+	if err != nil {
+		fmt.Printf("bad episode map: %v\n", err)
+		os.Exit(1)
+	}
+
+	return &file.AbsoluteFinder{
+		Pattern: patternFromFlags(cmd, fsys, dir, file.InferAbsolutePattern),
+		Map:     episodeMap,
+	}
+}
+
+// optionsFromFlags collects the options for renaming files of kind. Without
+// --output-template, the default template for kind is used, laid out as a
+// library if --dest was given.
+func optionsFromFlags(cmd *cobra.Command, kind file.Kind) file.Options {
+	opts := file.Options{
+		Template:  cmd.Flag(OutputFlagName).Value.String(),
+		Policy:    policyFromFlags(cmd),
+		Dest:      cmd.Flag(DestFlagName).Value.String(),
+		Sanitize:  sanitizeFromFlags(cmd),
+		MatchPath: matchPathFromFlags(cmd),
+		Normalize: normalizeFromFlags(cmd),
+	}
+	if opts.Template == "" {
+		if opts.Dest != "" {
+			opts.Template = file.LibraryTemplates[kind]
+		} else {
+			opts.Template = file.DefaultTemplates[kind]
+		}
+	}
+	return opts
+}
+
+// normalizeFromFlags returns the normalizer with the steps given by the
+// --normalize flag, using the apostrophes of the profile, or nil without any.
+func normalizeFromFlags(cmd *cobra.Command) *file.Normalizer {
+	normalizer, err := file.ParseNormalizer(cmd.Flag(NormalizeFlagName).Value.String())
+	if err != nil {
+		fmt.Printf("--%s: %v\n", NormalizeFlagName, err)
+		os.Exit(1)
+	}
+	if normalizer != nil && len(profile.Apostrophes) > 0 {
+		normalizer.Dictionary = make(map[string]string, len(profile.Apostrophes))
+		for k, v := range profile.Apostrophes {
+			normalizer.Dictionary[strings.ToLower(k)] = v
+		}
+	}
+	return normalizer
+}
+
+// matchPathFromFlags reports whether patterns are matched against paths, as
+// given by the --match-path flag or the profile.
+func matchPathFromFlags(cmd *cobra.Command) bool {
+	return cmd.Flag(MatchPathFlagName).Value.String() == "true"
+}
+
+func policyFromFlags(cmd *cobra.Command) file.ConflictPolicy {
+	policy, err := file.ParseConflictPolicy(cmd.Flag(ConflictFlagName).Value.String())
+	if err != nil {
+		fmt.Printf("--%s: %v\n", ConflictFlagName, err)
+		os.Exit(1)
+	}
+	return policy
+}
+
+func sanitizeFromFlags(cmd *cobra.Command) *file.SanitizeProfile {
+	profile, err := file.ParseSanitizeProfile(cmd.Flag(SanitizeFlagName).Value.String())
+	if err != nil {
+		fmt.Printf("--%s: %v\n", SanitizeFlagName, err)
+		os.Exit(1)
+	}
+	return profile
+}
+
+// journalPath returns the journal file selected by the --journal flag,
+// or the default location if it was not provided.
+func journalPath(cmd *cobra.Command) string {
+	if v := cmd.Flag(JournalFlagName).Value.String(); v != "" {
+		return v
+	}
+	path, err := file.DefaultJournalPath()
+	if err != nil {
+		fmt.Printf("journal: %v\n", err)
+		os.Exit(1)
+	}
+	return path
 }
 
 func Execute() {
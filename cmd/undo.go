@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/elliotcubit/renamer/pkg/file"
+	"github.com/spf13/cobra"
+)
+
+const (
+	ListFlagName = "list"
+)
+
+func init() {
+	undoCmd.Flags().Bool(ListFlagName, false, "List the runs in the journal instead of undoing one")
+
+	rootCmd.AddCommand(undoCmd)
+}
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [run]",
+	Short: "Revert the renames made by a run, the most recent one by default.",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := journalPath(cmd)
+
+		if cmd.Flag(ListFlagName).Changed {
+			entries, err := file.ReadJournal(path)
+			if err != nil {
+				fmt.Printf("journal: %v\n", err)
+				os.Exit(1)
+			}
+			counts := make(map[string]int)
+			for _, v := range entries {
+				counts[v.Run] += 1
+			}
+			for _, run := range file.JournalRuns(entries) {
+				fmt.Printf("%s\t%d files\n", run, counts[run])
+			}
+			return
+		}
+
+		var run string
+		if len(args) > 0 {
+			run = args[0]
+		}
+
+		err := file.Undo(path, run, cmd.Flag(DryRunFlagName).Changed)
+		if err != nil {
+			fmt.Printf("undo: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
@@ -5,6 +5,7 @@ go 1.18
 require (
 	github.com/spf13/cobra v1.7.0
 	golang.org/x/exp v0.0.0-20230425010034-47ecfdc1ba53
+	golang.org/x/text v0.13.0
 )
 
 require (
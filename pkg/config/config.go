@@ -0,0 +1,109 @@
+// Package config reads renamer's configuration file, which holds named
+// profiles of settings so that they needn't be given as flags every time.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Config is the contents of a configuration file.
+type Config struct {
+	// DefaultProfile is the profile used when none is selected.
+	DefaultProfile string `json:"default_profile,omitempty"`
+	// Profiles are the named sets of settings that can be selected.
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// Profile holds settings that would otherwise be given as flags. Empty
+// fields are left to the flags' defaults.
+type Profile struct {
+	Kind string `json:"kind,omitempty"`
+	// Patterns are tried in order, and the first that every file matches
+	// is used, as with the included patterns.
+	Patterns []string `json:"patterns,omitempty"`
+	Template string   `json:"template,omitempty"`
+	// Defaults are the values of capture groups that the patterns don't
+	// capture, such as "name" or "season".
+	Defaults   map[string]string `json:"defaults,omitempty"`
+	Dest       string            `json:"dest,omitempty"`
+	OnConflict string            `json:"on_conflict,omitempty"`
+	Sanitize   string            `json:"sanitize,omitempty"`
+	EpisodeMap string            `json:"episode_map,omitempty"`
+	Journal    string            `json:"journal,omitempty"`
+	// MatchPath matches the patterns against the path of each file
+	// relative to the directory, instead of its name.
+	MatchPath bool `json:"match_path,omitempty"`
+	// Normalize are the steps that captured names and titles are cleaned
+	// up with, such as "undot" or "title".
+	Normalize []string `json:"normalize,omitempty"`
+	// Apostrophes maps words to how they are written with apostrophes, for
+	// the "apostrophes" step, e.g. "greys" to "grey's".
+	Apostrophes map[string]string `json:"apostrophes,omitempty"`
+}
+
+// DefaultPath returns the configuration file location under
+// $XDG_CONFIG_HOME, falling back to ~/.config.
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "renamer", "config.json"), nil
+}
+
+// DefaultPatternsPath returns the directory of pattern library files next
+// to the default configuration file.
+func DefaultPatternsPath() (string, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "patterns"), nil
+}
+
+// Load decodes the configuration file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := new(Config)
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Profile returns the profile called name, or the default profile if name
+// is empty. Without a default profile, an empty name gives an empty profile.
+func (c *Config) Profile(name string) (Profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+		if name == "" {
+			return Profile{}, nil
+		}
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("no profile %q, have %v", name, c.ProfileNames())
+	}
+	return profile, nil
+}
+
+// ProfileNames returns the names of the profiles in c, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for k := range c.Profiles {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{
+		"default_profile": "tv",
+		"profiles": {
+			"tv": {
+				"patterns": ["(?P<name>.+) (?P<episode>\\d+)\\.mkv"],
+				"template": "{{ .ShowName }} {{ .Episode }}",
+				"defaults": {"season": "1"},
+				"dest": "/media/tv",
+				"on_conflict": "skip",
+				"match_path": true,
+				"normalize": ["undot", "title"],
+				"apostrophes": {"greys": "grey's"}
+			},
+			"movies": {"kind": "movie"}
+		}
+	}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	profile, err := config.Profile("")
+	if err != nil {
+		t.Fatalf("default profile: %v", err)
+	}
+	expected := Profile{
+		Patterns:    []string{`(?P<name>.+) (?P<episode>\d+)\.mkv`},
+		Template:    "{{ .ShowName }} {{ .Episode }}",
+		Defaults:    map[string]string{"season": "1"},
+		Dest:        "/media/tv",
+		OnConflict:  "skip",
+		MatchPath:   true,
+		Normalize:   []string{"undot", "title"},
+		Apostrophes: map[string]string{"greys": "grey's"},
+	}
+	if !reflect.DeepEqual(profile, expected) {
+		t.Errorf("expected %+v, got %+v", expected, profile)
+	}
+
+	profile, err = config.Profile("movies")
+	if err != nil || profile.Kind != "movie" {
+		t.Errorf("expected the movies profile, got %+v, %v", profile, err)
+	}
+
+	if _, err := config.Profile("anime"); err == nil {
+		t.Errorf("expected an error for a missing profile")
+	}
+}
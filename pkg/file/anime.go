@@ -0,0 +1,158 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Absolute is an episode numbered from the start of the show rather than
+// the start of its season, as is common for anime.
+type Absolute struct {
+	ShowName string `regexps:"name,required" json:"name"`
+	Number   int    `regexps:"absolute,required" json:"absolute"`
+	// NumberEnd is the last episode in a file holding several, or zero.
+	NumberEnd int    `regexps:"absolute_end" json:"absolute_end,omitempty"`
+	Title     string `regexps:"title" json:"title"`
+	// Special is set to the marker of a special, such as "SP" or "OVA", in
+	// which case Number counts specials rather than episodes.
+	Special string `regexps:"special" json:"special,omitempty"`
+}
+
+// SeasonRange maps the absolute episodes First through Last to a season.
+// A Last of zero means the season runs on until the next range.
+type SeasonRange struct {
+	Season int `json:"season"`
+	First  int `json:"first"`
+	Last   int `json:"last,omitempty"`
+}
+
+// EpisodeMap holds the season ranges of each show, keyed by show name.
+type EpisodeMap map[string][]SeasonRange
+
+// LoadEpisodeMap reads an episode map from a JSON file of the form
+//
+//	{"Show": [{"season": 1, "first": 1, "last": 24}, {"season": 2, "first": 25}]}
+func LoadEpisodeMap(path string) (EpisodeMap, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m EpisodeMap
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Lookup converts an absolute episode number of show to its season and
+// episode. Show names are compared case-insensitively.
+func (m EpisodeMap) Lookup(show string, absolute int) (season, episode int, ok bool) {
+	var ranges []SeasonRange
+	for k, v := range m {
+		if strings.EqualFold(strings.TrimSpace(k), strings.TrimSpace(show)) {
+			ranges = v
+			break
+		}
+	}
+
+	var best *SeasonRange
+	for i, v := range ranges {
+		if absolute < v.First || (v.Last != 0 && absolute > v.Last) {
+			continue
+		}
+		if best == nil || v.First > best.First {
+			best = &ranges[i]
+		}
+	}
+	if best == nil {
+		return 0, 0, false
+	}
+	return best.Season, absolute - best.First + 1, true
+}
+
+// AbsoluteFinder matches file names with absolute episode numbers, and uses
+// an episode map to turn them into a Match.
+type AbsoluteFinder struct {
+	Pattern Finder[Absolute]
+	Map     EpisodeMap
+}
+
+// FindString returns the Match for s, or nil if s doesn't match the pattern
+// or its episode isn't in the map.
+func (f *AbsoluteFinder) FindString(s string) *Match {
+	match, _ := f.ParseString(s)
+	return match
+}
+
+// ParseString is FindString, with the reason if there is no Match.
+func (f *AbsoluteFinder) ParseString(s string) (*Match, error) {
+	return f.ParseStringContext(s, nil)
+}
+
+// ParseStringContext is ParseString with the context of s for the defaults
+// of the pattern.
+func (f *AbsoluteFinder) ParseStringContext(s string, context map[string]string) (*Match, error) {
+	match, _, _, err := f.find(s, context)
+	return match, err
+}
+
+func (f *AbsoluteFinder) find(s string, context map[string]string) (*Match, string, map[string]string, error) {
+	abs, pattern, groups, err := find(f.Pattern, s, context)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	match, err := f.lookup(abs)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return match, pattern, groups, nil
+}
+
+// lookup turns abs into a Match with the episode map. Ranges of episodes
+// that span seasons are rejected, as a file can only be in one.
+func (f *AbsoluteFinder) lookup(abs *Absolute) (*Match, error) {
+	season, episode, ok := 0, abs.Number, true
+	if abs.Special == "" {
+		season, episode, ok = f.Map.Lookup(abs.ShowName, abs.Number)
+	}
+	if !ok {
+		return nil, fmt.Errorf("episode %d of %q is not in the episode map", abs.Number, abs.ShowName)
+	}
+
+	match := &Match{
+		ShowName: abs.ShowName,
+		Season:   season,
+		Episode:  episode,
+		Title:    abs.Title,
+	}
+	if abs.NumberEnd <= abs.Number {
+		return match, nil
+	}
+	if abs.Special != "" {
+		match.EpisodeEnd = episode + abs.NumberEnd - abs.Number
+		return match, nil
+	}
+
+	endSeason, endEpisode, ok := f.Map.Lookup(abs.ShowName, abs.NumberEnd)
+	if !ok {
+		return nil, fmt.Errorf("episode %d of %q is not in the episode map", abs.NumberEnd, abs.ShowName)
+	}
+	if endSeason != season {
+		return nil, fmt.Errorf("episodes %d-%d of %q span seasons %d and %d", abs.Number, abs.NumberEnd, abs.ShowName, season, endSeason)
+	}
+	match.EpisodeEnd = endEpisode
+	return match, nil
+}
+
+// MatchingPattern returns the name of the pattern that matches s, if the
+// pattern is a PerFile.
+func (f *AbsoluteFinder) MatchingPattern(s string, context map[string]string) string {
+	_, pattern, _, _ := find(f.Pattern, s, context)
+	return pattern
+}
+
+func (f *AbsoluteFinder) String() string {
+	return f.Pattern.String()
+}
@@ -0,0 +1,48 @@
+package file
+
+import "testing"
+
+func TestAbsoluteFinder(t *testing.T) {
+	finder := &AbsoluteFinder{
+		Pattern: absolutePatterns[0].Regexp,
+		Map: EpisodeMap{
+			"Show": {
+				{Season: 1, First: 1, Last: 12},
+				{Season: 2, First: 13},
+			},
+		},
+	}
+
+	type testcase struct {
+		name     string
+		season   int
+		episodes string
+		matches  bool
+	}
+
+	tests := []testcase{
+		{"[Group] Show - 05 [1080p].mkv", 1, "e05", true},
+		{"[Group] Show - 137 [1080p].mkv", 2, "e125", true},
+		{"[Group] show - 13-14v2 (720p) [ABCD1234].mkv", 2, "e01-e02", true},
+		{"[Group] Show - 11-12 [1080p].mkv", 1, "e11-e12", true},
+		{"[Group] Show - 12-13 [1080p].mkv", 0, "", false},
+		{"[Group] Show - SP03 [1080p].mkv", 0, "e03", true},
+		{"[Group] Show - OVA [1080p].mkv", 0, "e01", true},
+		{"[Group] Other Show - 05 [1080p].mkv", 0, "", false},
+		{"Show.S01E05.mkv", 0, "", false},
+	}
+
+	for _, test := range tests {
+		match := finder.FindString(test.name)
+		if (match != nil) != test.matches {
+			t.Errorf("match on %q: got %+v", test.name, match)
+			continue
+		}
+		if match == nil {
+			continue
+		}
+		if match.Season != test.season || match.Episodes() != test.episodes {
+			t.Errorf("wrong match for %q, got: %+v", test.name, match)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictPolicy decides what happens to a rename whose target is already
+// taken, either by a file on disk or by another rename in the same plan.
+type ConflictPolicy string
+
+const (
+	// ConflictAbort refuses to rename anything if there is a conflict.
+	ConflictAbort ConflictPolicy = "abort"
+	// ConflictSkip leaves conflicting files where they are.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictSuffix appends a counter to the target, e.g. "Name (2).mkv".
+	ConflictSuffix ConflictPolicy = "suffix"
+	// ConflictOverwrite replaces files that are already at the target. A
+	// target claimed by another rename in the same plan is skipped instead,
+	// since overwriting it would lose the other source.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+var ConflictPolicies = []ConflictPolicy{
+	ConflictAbort,
+	ConflictSkip,
+	ConflictSuffix,
+	ConflictOverwrite,
+}
+
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	for _, v := range ConflictPolicies {
+		if string(v) == s {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("unknown conflict policy %q", s)
+}
+
+// Conflict describes a rename whose target was already taken, and what
+// was done about it.
+type Conflict struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// Other is the source of the rename that claimed Target first, or
+	// empty if Target already exists on disk.
+	Other string `json:"other,omitempty"`
+	// Resolution is the target that was used instead, or empty if the
+	// rename was dropped.
+	Resolution string `json:"resolution,omitempty"`
+}
+
+func (c Conflict) String() string {
+	reason := "already exists"
+	if c.Other != "" {
+		reason = fmt.Sprintf("is also the target of %q", c.Other)
+	}
+	return fmt.Sprintf("%q -> %q: target %s", c.Source, c.Target, reason)
+}
+
+// ConflictError is returned by ResolveConflicts under ConflictAbort.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	lines := make([]string, len(e.Conflicts))
+	for i, v := range e.Conflicts {
+		lines[i] = v.String()
+	}
+	return fmt.Sprintf("%d conflicting renames:\n  %s", len(e.Conflicts), strings.Join(lines, "\n  "))
+}
+
+// ResolveConflicts checks every target in renames against the other renames
+// and the files on disk, and applies policy to the ones that conflict.
+// Renames earlier in the list claim their target first. It returns the
+// renames to perform along with every conflict that was found.
+func ResolveConflicts(renames []Rename, policy ConflictPolicy) ([]Rename, []Conflict, error) {
+	claimed := make(map[string]string, len(renames))
+	var conflicts []Conflict
+	retv := make([]Rename, 0, len(renames))
+
+	taken := func(source, target string) (string, bool) {
+		if other, ok := claimed[target]; ok {
+			return other, true
+		}
+		return "", targetExists(source, target)
+	}
+
+	// claim takes the target of v and its sidecars. Sidecars whose target
+	// is taken on disk are overwritten under ConflictOverwrite, and dropped
+	// otherwise, since their names follow from the target of v.
+	claim := func(v Rename) {
+		claimed[v.Target] = v.Source
+		v.setSidecarTargets()
+		sidecars := v.Sidecars[:0]
+		for _, sidecar := range v.Sidecars {
+			other, ok := taken(sidecar.Source, sidecar.Target)
+			if ok {
+				conflict := Conflict{Source: sidecar.Source, Target: sidecar.Target, Other: other}
+				if policy == ConflictOverwrite && other == "" {
+					conflict.Resolution = sidecar.Target
+				}
+				conflicts = append(conflicts, conflict)
+				if conflict.Resolution == "" {
+					continue
+				}
+			}
+			claimed[sidecar.Target] = sidecar.Source
+			sidecars = append(sidecars, sidecar)
+		}
+		v.Sidecars = sidecars
+		retv = append(retv, v)
+	}
+
+	for _, v := range renames {
+		// claim filters the sidecars in place, which mustn't touch renames
+		v.Sidecars = append([]Sidecar(nil), v.Sidecars...)
+		other, ok := taken(v.Source, v.Target)
+		if !ok {
+			claim(v)
+			continue
+		}
+
+		conflict := Conflict{Source: v.Source, Target: v.Target, Other: other}
+		switch policy {
+		case ConflictSkip:
+		case ConflictSuffix:
+			ext := filepath.Ext(v.Target)
+			base := strings.TrimSuffix(v.Target, ext)
+			for i := 2; ; i++ {
+				candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+				if _, ok := taken(v.Source, candidate); !ok {
+					v.Target = candidate
+					break
+				}
+			}
+			conflict.Resolution = v.Target
+		case ConflictOverwrite:
+			if other == "" {
+				conflict.Resolution = v.Target
+			}
+		}
+		conflicts = append(conflicts, conflict)
+		if conflict.Resolution != "" {
+			claim(v)
+		}
+	}
+
+	if len(conflicts) > 0 && (policy == ConflictAbort || policy == "") {
+		return nil, conflicts, &ConflictError{conflicts}
+	}
+	return retv, conflicts, nil
+}
+
+// targetExists reports whether there is a file at target other than source
+// itself, which would be the case for a rename that only changes case on a
+// case-insensitive filesystem. Targets that can't be checked are assumed
+// to exist.
+func targetExists(source, target string) bool {
+	info, err := os.Lstat(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	if sourceInfo, err := os.Lstat(source); err == nil && os.SameFile(info, sourceInfo) {
+		return false
+	}
+	return true
+}
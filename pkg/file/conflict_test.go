@@ -0,0 +1,99 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveConflicts(t *testing.T) {
+	dir := t.TempDir()
+	for _, v := range []string{"a.mkv", "b.mkv", "c.mkv", "existing.mkv"} {
+		if err := os.WriteFile(filepath.Join(dir, v), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	renames := []Rename{
+		{Source: path("a.mkv"), Target: path("new.mkv")},
+		{Source: path("b.mkv"), Target: path("new.mkv")},
+		{Source: path("c.mkv"), Target: path("existing.mkv")},
+	}
+
+	type testcase struct {
+		policy  ConflictPolicy
+		targets []string
+	}
+
+	tests := []testcase{
+		{ConflictAbort, nil},
+		{ConflictSkip, []string{"new.mkv"}},
+		{ConflictSuffix, []string{"new.mkv", "new (2).mkv", "existing (2).mkv"}},
+		{ConflictOverwrite, []string{"new.mkv", "existing.mkv"}},
+	}
+
+	for _, test := range tests {
+		resolved, conflicts, err := ResolveConflicts(renames, test.policy)
+		if len(conflicts) != 2 {
+			t.Errorf("%s: expected 2 conflicts, got %d", test.policy, len(conflicts))
+		}
+		if test.policy == ConflictAbort {
+			var conflictErr *ConflictError
+			if !errors.As(err, &conflictErr) {
+				t.Errorf("%s: expected conflict error, got %v", test.policy, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.policy, err)
+			continue
+		}
+		if len(resolved) != len(test.targets) {
+			t.Errorf("%s: expected %d renames, got %d", test.policy, len(test.targets), len(resolved))
+			continue
+		}
+		for i, v := range resolved {
+			if v.Target != path(test.targets[i]) {
+				t.Errorf("%s: expected target %q, got %q", test.policy, path(test.targets[i]), v.Target)
+			}
+		}
+	}
+}
+
+func TestOverwriteKeepsPlannedTargets(t *testing.T) {
+	dir := t.TempDir()
+	for _, v := range []string{"A.S01E01.720p.mkv", "A.S01E01.1080p.mkv"} {
+		if err := os.WriteFile(filepath.Join(dir, v), []byte(v), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan, err := NewPlan[Match](os.DirFS(dir), dir, patterns[1], Options{
+		Template: "{{ .ShowName }} - s{{ pad 2 .Season }}{{ .Episodes }}",
+		Policy:   ConflictOverwrite,
+	})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(plan.Renames) != 1 || len(plan.Conflicts) != 1 {
+		t.Fatalf("expected 1 rename and 1 conflict, got %d and %d", len(plan.Renames), len(plan.Conflicts))
+	}
+	if conflict := plan.Conflicts[0]; conflict.Resolution != "" || conflict.Other != plan.Renames[0].Source {
+		t.Errorf("expected the second rename to be skipped, got %s", conflict)
+	}
+
+	if err := plan.Apply(nil); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected both files to be kept, got %d", len(entries))
+	}
+}
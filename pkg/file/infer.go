@@ -4,7 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"path/filepath"
+	"os"
 	"strings"
 
 	"github.com/elliotcubit/renamer/pkg/regexps"
@@ -12,17 +12,99 @@ import (
 
 var errCantInfer = errors.New("cannot infer pattern of file names")
 
-var rawPatterns = []string{
-	`(?P<name>[^-]+) - \[(?P<season>\d+)x(?P<episode>\d+)\] - (?P<title>.*)\....`,
-	`(?P<name>[^\.]+)\.S(?P<season>\d+)E(?P<episode>\d\d)\.(?P<title>.*)\.?\d+p.*\....`,
+// Specials are matched by the same patterns as regular episodes, and put in
+// season 0. Specials without a number, such as a single OVA, are episode 1.
+// Dotted names are only taken for specials if they have no SxxEyy, so that
+// shows like "The.Special.Ops" keep their name. A special marker without a
+// number must be the last part of the name before the resolution, and none
+// can come after a year, so that movies like "Some.Movie.2019.Special.Edition"
+// aren't taken for specials.
+var rawPatterns = []PatternSpec{
+	{
+		Name:     "tv-bracketed",
+		Kind:     KindTV,
+		Pattern:  `(?P<name>[^-]+) - \[(?:(?P<season>\d+)x(?P<episode>\d+)(?:-(?:\d+x)?(?P<episode_end>\d+))?|(?i:SP|OVA|OAD|Special) ?(?P<episode>\d+)?)\](?: - (?P<title>.*))?\....`,
+		Defaults: specialDefaults,
+		Samples:  []string{"House - [4x04] - Guardian Angels.mp4", "Show - [SP01].mkv"},
+	},
+	{
+		Name: "tv-dotted",
+		Kind: KindTV,
+		Pattern: `^(?:(?P<name>.+?)\.S(?P<season>\d+)E(?P<episode>\d\d)(?:-?E?(?P<episode_end>\d\d))?(?:\.(?P<title>.*?))??` +
+			`|(?P<name>` + notYearToken + `(?:\.` + notYearToken + `)*?)\.(?i:SP|OVA|OAD|Special)(?:(?P<episode>\d+)(?:\.(?P<title>.*?))??)?)` +
+			`(?:\.?\d+p.*)?\.[^.]+$`,
+		Defaults: specialDefaults,
+		Samples:  []string{"You.S02E05.Dont.720p.mkv", "Show.S01E01-E02.mkv"},
+	},
 }
 
-var patterns []*regexps.Regexp[Match]
+// notYearToken matches a part of a dotted name that isn't a year.
+const notYearToken = `(?:[^.]*[^.\d][^.]*|\d{1,3}|\d{5,}|(?:[03-9]\d|1[0-8]|2[1-9])\d\d)`
+
+var specialDefaults = map[string]string{
+	"season":  "0",
+	"episode": "1",
+}
+
+var rawAbsolutePatterns = []PatternSpec{
+	{
+		Name:     "anime-fansub",
+		Kind:     KindAnime,
+		Pattern:  `^\[(?P<group>[^\]]+)\] ?(?P<name>.+?) - (?:(?P<absolute>\d{1,4})(?:-(?P<absolute_end>\d{1,4}))?(?:v\d)?|(?P<special>(?i:SP|OVA|OAD|Special)) ?(?P<absolute>\d{1,3})?)(?: - (?P<title>[^\[(]+?))?(?: *[\[(].*)?\.[^.]+$`,
+		Defaults: absoluteSpecialDefaults,
+		Samples:  []string{"[Group] Show - 137 [1080p].mkv", "[Group] Show - OVA.mkv"},
+	},
+}
+
+var absoluteSpecialDefaults = map[string]string{
+	"absolute": "1",
+}
+
+var rawDailyPatterns = []PatternSpec{
+	{
+		Name:    "daily-dotted",
+		Kind:    KindDaily,
+		Pattern: `^(?P<name>.+?)\.(?P<date>(?:19|20)\d\d\.\d\d\.\d\d)(?:\.(?P<title>.*?))??(?:\.\d{3,4}[pi].*)?\.[^.]+$`,
+		Samples: []string{"Show.2023.10.05.Guest.Name.720p.mkv"},
+	},
+}
+
+// The year of a dotted movie is the one right before its edition, resolution
+// or source, so that titles ending in a number like a year, such as
+// "Blade.Runner.2049.2017", keep it. Without any of those, it is the first.
+var rawMoviePatterns = []PatternSpec{
+	{
+		Name: "movie-dotted",
+		Kind: KindMovie,
+		Pattern: `^(?:(?P<title>.+?)\.\(?(?P<year>(?:19|20)\d\d)\)?(?:\.` + movieEdition + `)?\.(?:(?P<resolution>\d{3,4}[pi])|` + movieSource + `)\b(?:.*?(?P<resolution>\d{3,4}[pi]))?` +
+			`|(?P<title>.+?)\.\(?(?P<year>(?:19|20)\d\d)\)?\.(?:` + movieEdition + `\.)?(?:.*?(?P<resolution>\d{3,4}[pi]))?).*\.[^.]+$`,
+		Samples: []string{"Some.Movie.2019.1080p.BluRay.x264.mkv", "Blade.Runner.2049.2017.1080p.mkv"},
+	},
+	{
+		Name:    "movie-plex",
+		Kind:    KindMovie,
+		Pattern: `^(?P<title>[^(]+?) \((?P<year>(?:19|20)\d\d)\)(?: \{edition-(?P<edition>[^}]+)\})?(?:.*?(?P<resolution>\d{3,4}[pi]))?.*\.[^.]+$`,
+		Samples: []string{"Some Movie (2019).mkv", "Some Movie (2019) {edition-Director's Cut}.mkv"},
+	},
+}
+
+const (
+	movieEdition = `(?P<edition>(?i:directors?|extended|unrated|theatrical|final|special|ultimate|remastered)(?:\.(?i:cut|edition))?)`
+	movieSource  = `(?i:blu-?ray|bdrip|brrip|web|web-?dl|web-?rip|hdtv|dvdrip|hdrip|remux|uhd|4k|hdr|proper|repack|x26[45]|h\.?26[45]|hevc|xvid)`
+)
+
+var patterns []*Pattern[Match]
+var absolutePatterns []*Pattern[Absolute]
+var dailyPatterns []*Pattern[Daily]
+var moviePatterns []*Pattern[Movie]
 
 func init() {
-	patterns = make([]*regexps.Regexp[Match], len(rawPatterns))
-	for i, v := range rawPatterns {
-		patterns[i] = regexps.MustCompile[Match](v)
+	for _, v := range [][]PatternSpec{rawPatterns, rawAbsolutePatterns, rawDailyPatterns, rawMoviePatterns} {
+		for _, spec := range v {
+			if err := addPattern(spec, "built-in"); err != nil {
+				panic(err)
+			}
+		}
 	}
 }
 
@@ -32,34 +114,127 @@ func InferPattern(
 	fsys fs.FS,
 	dir string,
 ) (*regexps.Regexp[Match], error) {
+	return announce(inferPattern(fsys, dir, patterns))
+}
+
+// InferAbsolutePattern is InferPattern for directories of episodes with
+// absolute numbering.
+func InferAbsolutePattern(
+	fsys fs.FS,
+	dir string,
+) (*regexps.Regexp[Absolute], error) {
+	return announce(inferPattern(fsys, dir, absolutePatterns))
+}
+
+// InferDailyPattern is InferPattern for directories of daily shows.
+func InferDailyPattern(
+	fsys fs.FS,
+	dir string,
+) (*regexps.Regexp[Daily], error) {
+	return announce(inferPattern(fsys, dir, dailyPatterns))
+}
+
+// InferMoviePattern is InferPattern for directories of movies.
+func InferMoviePattern(
+	fsys fs.FS,
+	dir string,
+) (*regexps.Regexp[Movie], error) {
+	return announce(inferPattern(fsys, dir, moviePatterns))
+}
+
+// ChoosePattern is InferPattern for a list of patterns given by the user,
+// such as those of a configuration profile.
+func ChoosePattern[T any](
+	fsys fs.FS,
+	dir string,
+	patterns []*Pattern[T],
+) (*regexps.Regexp[T], error) {
+	return announce(inferPattern(fsys, dir, patterns))
+}
+
+// ChoosePathPattern is ChoosePattern for patterns that match the paths of
+// files relative to dir, with "/" between their parts, instead of their
+// names.
+func ChoosePathPattern[T any](
+	fsys fs.FS,
+	dir string,
+	patterns []*Pattern[T],
+) (*regexps.Regexp[T], error) {
+	return announce(bestCandidate(RankPathPatterns(fsys, dir, patterns)))
+}
 
-	matched := make([]bool, len(patterns))
-	for i := range matched {
-		matched[i] = true
+// InferKind works out whether a directory holds TV, anime, daily shows or
+// movies, by checking which kind's patterns all of its files match, in that
+// order. A kind matches even if it's ambiguous which of its patterns to use.
+func InferKind(
+	fsys fs.FS,
+	dir string,
+) (Kind, error) {
+	if matchesKind(inferPattern(fsys, dir, patterns)) {
+		return KindTV, nil
+	}
+	if matchesKind(inferPattern(fsys, dir, absolutePatterns)) {
+		return KindAnime, nil
+	}
+	if matchesKind(inferPattern(fsys, dir, dailyPatterns)) {
+		return KindDaily, nil
+	}
+	if matchesKind(inferPattern(fsys, dir, moviePatterns)) {
+		return KindMovie, nil
 	}
+	return "", errCantInfer
+}
 
-	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
-		if d.IsDir() {
-			return nil
-		}
-		_, file := filepath.Split(path)
-		for i, v := range patterns {
-			matched[i] = matched[i] && (v.MatchString(file) || isOkay(file))
-		}
-		return nil
-	})
+func matchesKind[T any](_ *Pattern[T], err error) bool {
+	var ambiguous *AmbiguousPatternError
+	return err == nil || errors.As(err, &ambiguous)
+}
+
+func announce[T any](pattern *Pattern[T], err error) (*regexps.Regexp[T], error) {
 	if err != nil {
 		return nil, err
 	}
+	fmt.Fprintf(os.Stderr, "Using detected pattern %s %q\n", pattern.Name, pattern.Regexp)
+	return pattern.Regexp, nil
+}
 
-	for i, v := range matched {
-		if v {
-			fmt.Printf("Using detected pattern %q\n", rawPatterns[i])
-			return patterns[i], nil
-		}
+// inferPattern returns the best of patterns for the files in fsys, which
+// must match every file. If several patterns of the same priority do, their
+// scores are too close to choose between them, and they extract different
+// fields from the files, an *AmbiguousPatternError is returned.
+func inferPattern[T any](
+	fsys fs.FS,
+	dir string,
+	patterns []*Pattern[T],
+) (*Pattern[T], error) {
+	return bestCandidate(RankPatterns(fsys, dir, patterns))
+}
+
+// bestCandidate returns the pattern of the first of candidates, as ranked by
+// RankPatterns, if it is the pattern to use as inferPattern describes.
+func bestCandidate[T any](candidates []*Candidate[T], err error) (*Pattern[T], error) {
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 || len(candidates[0].Examples) == 0 {
+		return nil, errCantInfer
 	}
 
-	return nil, errCantInfer
+	best := candidates[0]
+	if best.Coverage() < 1 {
+		return nil, fmt.Errorf("%w: the closest, %s, matches %d of %d files", errCantInfer, best.Pattern.Name, best.Matched, best.Total)
+	}
+
+	tied := []*Candidate[T]{best}
+	for _, v := range candidates[1:] {
+		if v.Coverage() == 1 && v.Pattern.Priority == best.Pattern.Priority && best.Score-v.Score < ambiguityMargin && !best.sameMatches(v) {
+			tied = append(tied, v)
+		}
+	}
+	if len(tied) > 1 {
+		return nil, newAmbiguousPatternError(tied)
+	}
+	return best.Pattern, nil
 }
 
 // It is okay if these files don't match the patterns :)
@@ -78,3 +253,18 @@ func isOkay(fname string) bool {
 	}
 	return false
 }
+
+// listFiles returns the paths of every file in fsys.
+func listFiles(fsys fs.FS) ([]string, error) {
+	var paths []string
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
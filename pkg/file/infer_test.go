@@ -2,6 +2,8 @@ package file
 
 import (
 	"io/fs"
+	"path/filepath"
+	"strings"
 	"testing"
 	"testing/fstest"
 )
@@ -47,3 +49,202 @@ func TestInferFilenames(t *testing.T) {
 
 	}
 }
+
+func TestInferMovies(t *testing.T) {
+	type testcase struct {
+		name    string
+		year    int
+		edition string
+		target  string
+	}
+
+	tests := []testcase{
+		{"Some.Movie.2019.1080p.BluRay.x264.mkv", 2019, "", "Some Movie (2019)/Some Movie (2019).mkv"},
+		{"Other.Film.1999.Directors.Cut.720p.WEB.mkv", 1999, "Directors.Cut", "Other Film (1999)/Other Film (1999) {edition-Directors Cut}.mkv"},
+		{"Blade.Runner.2049.2017.1080p.mkv", 2017, "", "Blade Runner 2049 (2017)/Blade Runner 2049 (2017).mkv"},
+		{"2001.A.Space.Odyssey.1968.Remastered.BluRay.mkv", 1968, "Remastered", "2001 A Space Odyssey (1968)/2001 A Space Odyssey (1968) {edition-Remastered}.mkv"},
+		{"Some.Movie.2019.German.DL.1080p.mkv", 2019, "", "Some Movie (2019)/Some Movie (2019).mkv"},
+		{"Plain Movie (2001).mkv", 2001, "", "Plain Movie (2001)/Plain Movie (2001).mkv"},
+		{"Plain Movie (2001) {edition-Extended}.mkv", 2001, "Extended", "Plain Movie (2001)/Plain Movie (2001) {edition-Extended}.mkv"},
+	}
+
+	for _, test := range tests {
+		fs := wrapNamesInFS([]string{test.name})
+		kind, err := InferKind(fs, ".")
+		if err != nil {
+			t.Fatalf("infer kind on %q: %v", test.name, err)
+		}
+		if kind != KindMovie {
+			t.Errorf("infer kind on %q: got %q", test.name, kind)
+		}
+
+		pat, err := InferMoviePattern(fs, ".")
+		if err != nil {
+			t.Fatalf("infer on %q: %v", test.name, err)
+		}
+		match := pat.FindString(test.name)
+		if match == nil {
+			t.Fatalf("no match for %q", test.name)
+		}
+		if match.Year != test.year || match.Edition != test.edition {
+			t.Errorf("wrong match for %q, got: %+v", test.name, match)
+		}
+
+		renames, err := PlanRenames[Movie](fs, ".", pat, Options{Template: DefaultTemplates[KindMovie]})
+		if err != nil || len(renames) != 1 {
+			t.Fatalf("plan %q: %v, %v", test.name, renames, err)
+		}
+		if !strings.HasSuffix(renames[0].Target, filepath.FromSlash("/"+test.target)) {
+			t.Errorf("rename %q: expected %q, got %q", test.name, test.target, renames[0].Target)
+		}
+	}
+}
+
+func TestInferEpisodes(t *testing.T) {
+	type testcase struct {
+		name     string
+		episodes string
+		title    string
+	}
+
+	tests := []testcase{
+		{"House - [4x04] - Guardian Angels.mp4", "e04", "Guardian Angels"},
+		{"House - [1x01-02].mp4", "e01-e02", ""},
+		{"House - [1x01-1x02] - Pilot.mp4", "e01-e02", "Pilot"},
+		{"You.S02E05.Dont.720p.mkv", "e05", "Dont"},
+		{"You.S02E05.Dont1024p.skv", "e05", "Dont"},
+		{"Show.S01E01E02.mkv", "e01-e02", ""},
+		{"Show.S01E01-E02.Pilot.1080p.WEB.mkv", "e01-e02", "Pilot"},
+		{"Show.S00E03.Making.Of.720p.mkv", "e03", "Making.Of"},
+		{"Show.SP02.Recap.720p.mkv", "e02", "Recap"},
+		{"Show.OVA.mkv", "e01", ""},
+		{"House - [Special 2] - Behind the Scenes.mp4", "e02", "Behind the Scenes"},
+	}
+
+	for _, test := range tests {
+		pat, err := InferPattern(wrapNamesInFS([]string{test.name}), ".")
+		if err != nil {
+			t.Fatalf("infer on %q: %v", test.name, err)
+		}
+		match := pat.FindString(test.name)
+		if match == nil {
+			t.Fatalf("no match for %q", test.name)
+		}
+		if match.Episodes() != test.episodes || match.Title != test.title {
+			t.Errorf("wrong match for %q, got: %+v", test.name, match)
+		}
+	}
+}
+
+func TestInferDaily(t *testing.T) {
+	name := "The.Show.2023.10.05.Guest.Name.720p.mkv"
+	fs := wrapNamesInFS([]string{name})
+
+	kind, err := InferKind(fs, ".")
+	if err != nil {
+		t.Fatalf("infer kind on %q: %v", name, err)
+	}
+	if kind != KindDaily {
+		t.Errorf("infer kind on %q: got %q", name, kind)
+	}
+
+	pat, err := InferDailyPattern(fs, ".")
+	if err != nil {
+		t.Fatalf("infer on %q: %v", name, err)
+	}
+	match := pat.FindString(name)
+	if match == nil {
+		t.Fatalf("no match for %q", name)
+	}
+	if match.ShowName != "The.Show" || match.Date.Format("2006-01-02") != "2023-10-05" || match.Title != "Guest.Name" {
+		t.Errorf("wrong match for %q, got: %+v", name, match)
+	}
+
+	renames, err := PlanRenames[Daily](fs, ".", pat, Options{Template: DefaultTemplates[KindDaily]})
+	if err != nil || len(renames) != 1 {
+		t.Fatalf("plan %q: %v, %v", name, renames, err)
+	}
+	if expected := "The Show - 2023-10-05 - Guest Name.mkv"; filepath.Base(renames[0].Target) != expected {
+		t.Errorf("rename %q: expected %q, got %q", name, expected, renames[0].Target)
+	}
+}
+
+func TestInferSpecials(t *testing.T) {
+	names := []string{
+		"You.S02E05.Dont.720p.mkv",
+		"You.S02E06.Another.720p.mkv",
+		"You.SP01.Behind.The.Scenes.720p.mkv",
+	}
+
+	pat, err := InferPattern(wrapNamesInFS(names), ".")
+	if err != nil {
+		t.Fatalf("infer with specials: %v", err)
+	}
+
+	match := pat.FindString(names[2])
+	if match == nil {
+		t.Fatalf("no match for %q", names[2])
+	}
+	if match.Season != 0 || match.SeasonFolder() != "Specials" {
+		t.Errorf("special not in season 0, got: %+v", match)
+	}
+
+	match = pat.FindString(names[0])
+	if match == nil {
+		t.Fatalf("no match for %q", names[0])
+	}
+	if match.Season != 2 || match.SeasonFolder() != "Season 02" {
+		t.Errorf("wrong season, got: %+v", match)
+	}
+}
+
+func TestInferSpecialInName(t *testing.T) {
+	type testcase struct {
+		name     string
+		show     string
+		season   int
+		episodes string
+		title    string
+	}
+
+	tests := []testcase{
+		{"The.Special.Ops.S01E01.Pilot.mkv", "The.Special.Ops", 1, "e01", "Pilot"},
+		{"Ova.Land.S02E03.720p.mkv", "Ova.Land", 2, "e03", ""},
+		{"Show.Specialist.SP02.Recap.mkv", "Show.Specialist", 0, "e02", "Recap"},
+	}
+
+	for _, test := range tests {
+		pat, err := InferPattern(wrapNamesInFS([]string{test.name}), ".")
+		if err != nil {
+			t.Fatalf("infer on %q: %v", test.name, err)
+		}
+		match := pat.FindString(test.name)
+		if match == nil {
+			t.Fatalf("no match for %q", test.name)
+		}
+		if match.ShowName != test.show || match.Season != test.season || match.Episodes() != test.episodes || match.Title != test.title {
+			t.Errorf("wrong match for %q, got: %+v", test.name, match)
+		}
+	}
+}
+
+func TestInferSpecialNotMovie(t *testing.T) {
+	names := []string{
+		"Some.Movie.2019.Special.Edition.1080p.mkv",
+		"The.Special.2019.1080p.BluRay.x264.mkv",
+	}
+
+	for _, name := range names {
+		fs := wrapNamesInFS([]string{name})
+		if match := patterns[1].FindString(name); match != nil {
+			t.Errorf("%q: expected no special, got: %+v", name, match)
+		}
+		kind, err := InferKind(fs, ".")
+		if err != nil {
+			t.Fatalf("infer kind on %q: %v", name, err)
+		}
+		if kind != KindMovie {
+			t.Errorf("infer kind on %q: got %q", name, kind)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var errNoRuns = errors.New("journal has no runs to undo")
+
+// JournalEntry records a single rename so that it can be reverted later.
+type JournalEntry struct {
+	Run      string    `json:"run"`
+	Old      string    `json:"old"`
+	New      string    `json:"new"`
+	Time     time.Time `json:"time"`
+	Pattern  string    `json:"pattern"`
+	Template string    `json:"template"`
+}
+
+// Journal appends the renames of a single run to a journal file.
+type Journal struct {
+	path     string
+	run      string
+	pattern  string
+	template string
+}
+
+// DefaultJournalPath returns the journal location under $XDG_STATE_HOME,
+// falling back to ~/.local/state.
+func DefaultJournalPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "renamer", "journal.jsonl"), nil
+}
+
+// NewJournal starts a new run that records into the journal at path.
+func NewJournal(path, pattern, template string) *Journal {
+	return &Journal{
+		path:     path,
+		run:      time.Now().UTC().Format("20060102T150405.000000"),
+		pattern:  pattern,
+		template: template,
+	}
+}
+
+// Run returns the ID of the run being recorded.
+func (j *Journal) Run() string {
+	return j.run
+}
+
+// Record appends a rename of oldPath to newPath to the journal.
+func (j *Journal) Record(oldPath, newPath string) error {
+	oldPath, err := filepath.Abs(oldPath)
+	if err != nil {
+		return err
+	}
+	newPath, err = filepath.Abs(newPath)
+	if err != nil {
+		return err
+	}
+
+	return appendEntries(j.path, []JournalEntry{{
+		Run:      j.run,
+		Old:      oldPath,
+		New:      newPath,
+		Time:     time.Now().UTC(),
+		Pattern:  j.pattern,
+		Template: j.template,
+	}})
+}
+
+func appendEntries(path string, entries []JournalEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, v := range entries {
+		if err := enc.Encode(v); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// ReadJournal returns every entry in the journal at path, oldest first.
+// A journal that does not exist yet is empty.
+func ReadJournal(path string) ([]JournalEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// JournalRuns returns the IDs of the runs in entries, oldest first.
+func JournalRuns(entries []JournalEntry) []string {
+	var runs []string
+	seen := make(map[string]bool)
+	for _, v := range entries {
+		if !seen[v.Run] {
+			seen[v.Run] = true
+			runs = append(runs, v.Run)
+		}
+	}
+	return runs
+}
+
+// Undo reverts the renames of a run in the journal at path, newest first.
+// If run is empty, the most recent run is undone. Entries that were reverted
+// are removed from the journal; if a rename fails, the entries that have not
+// been reverted yet are kept so that the undo can be retried.
+func Undo(path, run string, dry bool) error {
+	entries, err := ReadJournal(path)
+	if err != nil {
+		return err
+	}
+
+	if run == "" {
+		runs := JournalRuns(entries)
+		if len(runs) == 0 {
+			return errNoRuns
+		}
+		run = runs[len(runs)-1]
+	}
+
+	var undo []int
+	for i, v := range entries {
+		if v.Run == run {
+			undo = append(undo, i)
+		}
+	}
+	if len(undo) == 0 {
+		return fmt.Errorf("no run %q in journal", run)
+	}
+
+	if dry {
+		fmt.Printf("Undoing run %q would:\n", run)
+	}
+
+	undone := make(map[int]bool, len(undo))
+	for i := len(undo) - 1; i >= 0; i-- {
+		entry := entries[undo[i]]
+		if dry {
+			fmt.Printf("  Rename %q -> %q\n", entry.New, entry.Old)
+			continue
+		}
+		if _, err = os.Lstat(entry.Old); err == nil {
+			err = fmt.Errorf("undo %q: %q already exists", entry.New, entry.Old)
+			break
+		}
+		if err = move(entry.New, entry.Old); err != nil {
+			break
+		}
+		undone[undo[i]] = true
+	}
+
+	if dry || len(undone) == 0 {
+		return err
+	}
+
+	kept := make([]JournalEntry, 0, len(entries)-len(undone))
+	for i, v := range entries {
+		if !undone[i] {
+			kept = append(kept, v)
+		}
+	}
+	if werr := writeJournal(path, kept); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
+
+// writeJournal atomically replaces the journal at path with entries.
+func writeJournal(path string, entries []JournalEntry) error {
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := appendEntries(tmp, entries); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
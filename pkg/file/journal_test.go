@@ -0,0 +1,53 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	dir := t.TempDir()
+	journalPath := filepath.Join(dir, "state", "journal.jsonl")
+
+	oldPath := filepath.Join(dir, "You.S02E05.Dont.720p.mkv")
+	newPath := filepath.Join(dir, "You s2e5 - Dont.mkv")
+	if err := os.WriteFile(oldPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	journal := NewJournal(journalPath, "pattern", "template")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Record(oldPath, newPath); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	entries, err := ReadJournal(journalPath)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Run != journal.Run() {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	if err := Undo(journalPath, "", false); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if _, err := os.Stat(oldPath); err != nil {
+		t.Errorf("original file not restored: %v", err)
+	}
+
+	entries, err = ReadJournal(journalPath)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("undone entries left in journal: %+v", entries)
+	}
+
+	if err := Undo(journalPath, "", false); err == nil {
+		t.Error("undo of empty journal succeeded")
+	}
+}
@@ -0,0 +1,177 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/elliotcubit/renamer/pkg/regexps"
+)
+
+// Kind is the kind of media a directory holds, which decides the type that
+// file names are matched into and the default output template.
+type Kind string
+
+const (
+	KindTV    Kind = "tv"
+	KindMovie Kind = "movie"
+	// KindAnime is TV with absolute episode numbers, which are mapped to
+	// seasons with an EpisodeMap.
+	KindAnime Kind = "anime"
+	// KindDaily is TV named by air date, such as talk shows and news.
+	KindDaily Kind = "daily"
+)
+
+var Kinds = []Kind{
+	KindTV,
+	KindMovie,
+	KindAnime,
+	KindDaily,
+}
+
+func ParseKind(s string) (Kind, error) {
+	for _, v := range Kinds {
+		if string(v) == s {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("unknown kind %q", s)
+}
+
+// Media is the set of types that file names can be matched into.
+type Media interface {
+	Match | Daily | Movie
+}
+
+// Finder finds the fields of a file name. It is implemented by
+// *regexps.Regexp, and by types that convert what a pattern matched.
+type Finder[T any] interface {
+	FindString(s string) *T
+	String() string
+}
+
+// Parser is implemented by finders that can tell why they found nothing,
+// such as *regexps.Regexp.
+type Parser[T any] interface {
+	ParseString(s string) (*T, error)
+}
+
+// ContextParser is implemented by finders whose patterns have defaults that
+// can use the context of a file name, such as *regexps.Regexp.
+type ContextParser[T any] interface {
+	ParseStringContext(s string, context map[string]string) (*T, error)
+}
+
+// PathContext returns the context of the file at path for the defaults of
+// patterns: the names of the directory it is in, as "parent", and of the
+// one that is in, as "grandparent". For "Show/Season 3/01.mkv", the season
+// can default to {{ number .parent }} and the name to {{ .grandparent }}.
+func PathContext(path string) map[string]string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return dirContext(path)
+}
+
+// dirContext is PathContext without making path absolute, so a relative
+// path only has the context of the directories it names.
+func dirContext(path string) map[string]string {
+	parent := filepath.Dir(path)
+	return map[string]string{
+		"parent":      contextName(parent),
+		"grandparent": contextName(filepath.Dir(parent)),
+	}
+}
+
+// contextName is the name of the directory dir, or "" for the root or the
+// current directory.
+func contextName(dir string) string {
+	if filepath.Dir(dir) == dir {
+		return ""
+	}
+	return filepath.Base(dir)
+}
+
+// findString finds the fields of s with finder, with the reason if there
+// are none. context is given to finders that are a ContextParser.
+func findString[T any](finder Finder[T], s string, context map[string]string) (*T, error) {
+	if parser, ok := finder.(ContextParser[T]); ok {
+		return parser.ParseStringContext(s, context)
+	}
+	if parser, ok := finder.(Parser[T]); ok {
+		return parser.ParseString(s)
+	}
+	if match := finder.FindString(s); match != nil {
+		return match, nil
+	}
+	return nil, &regexps.NoMatchFoundError{}
+}
+
+// groupParser is implemented by finders that return the groups of what
+// they matched along with it, such as *regexps.Regexp.
+type groupParser[T any] interface {
+	ParseStringGroups(s string, context map[string]string) (*T, map[string]string, error)
+}
+
+// patternFinder is implemented by finders that use one of several patterns
+// for each file name, to return the name of the one used for s along with
+// what it matched and its groups.
+type patternFinder[T any] interface {
+	find(s string, context map[string]string) (*T, string, map[string]string, error)
+}
+
+// find finds the fields of s with finder, as findString does, along with
+// the name of the pattern that matched if finder uses one of several, and
+// the groups it captured if finder tells them. s is only matched once.
+func find[T any](finder Finder[T], s string, context map[string]string) (*T, string, map[string]string, error) {
+	if v, ok := finder.(patternFinder[T]); ok {
+		return v.find(s, context)
+	}
+	if v, ok := finder.(groupParser[T]); ok {
+		match, groups, err := v.ParseStringGroups(s, context)
+		return match, "", groups, err
+	}
+	match, err := findString(finder, s, context)
+	return match, "", nil, err
+}
+
+// skipReason describes why a file was skipped because of err.
+func skipReason(err error) string {
+	var noMatch *regexps.NoMatchFoundError
+	if errors.As(err, &noMatch) {
+		return "no pattern matched"
+	}
+	return err.Error()
+}
+
+const defaultTVTemplate = "{{ undot .ShowName }} - s{{ pad 2 .Season }}{{ .Episodes }}{{ with .Title }} - {{ undot . }}{{ end }}"
+
+// DefaultTemplates are the output templates used for each kind when none
+// is given. They follow Plex's naming conventions.
+var DefaultTemplates = map[Kind]string{
+	KindTV:    defaultTVTemplate,
+	KindAnime: defaultTVTemplate,
+	KindDaily: `{{ undot .ShowName }} - {{ .Date.Format "2006-01-02" }}{{ with .Title }} - {{ undot . }}{{ end }}`,
+	KindMovie: "{{ undot .Title }} ({{ .Year }})/{{ undot .Title }} ({{ .Year }}){{ with .Edition }} {edition-{{ undot . }}}{{ end }}",
+}
+
+// LibraryTemplates are the output templates used for each kind when files
+// are moved into a library with Options.Dest, laid out the way Plex expects.
+var LibraryTemplates = map[Kind]string{
+	KindTV:    "{{ undot .ShowName }}/{{ .SeasonFolder }}/" + defaultTVTemplate,
+	KindAnime: "{{ undot .ShowName }}/{{ .SeasonFolder }}/" + defaultTVTemplate,
+	KindDaily: "{{ undot .ShowName }}/Season {{ .Date.Year }}/" + DefaultTemplates[KindDaily],
+	KindMovie: DefaultTemplates[KindMovie],
+}
+
+// KindOf returns the kind of media that T holds.
+func KindOf[T Media]() Kind {
+	switch any(*new(T)).(type) {
+	case Daily:
+		return KindDaily
+	case Movie:
+		return KindMovie
+	default:
+		return KindTV
+	}
+}
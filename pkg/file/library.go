@@ -0,0 +1,166 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/elliotcubit/renamer/pkg/regexps"
+)
+
+// Pattern is a named pattern that file names of a kind are inferred with.
+type Pattern[T any] struct {
+	*regexps.Regexp[T]
+	Name string
+	// Priority orders patterns: those with a higher priority are tried
+	// first. The included patterns have priority 0.
+	Priority int
+	// Source is where the pattern was loaded from.
+	Source  string
+	Samples []string
+}
+
+// PatternSpec describes a pattern in a pattern library file.
+type PatternSpec struct {
+	Name     string            `json:"name"`
+	Kind     Kind              `json:"kind"`
+	Pattern  string            `json:"pattern"`
+	Priority int               `json:"priority,omitempty"`
+	Defaults map[string]string `json:"defaults,omitempty"`
+	// Samples are file names that the pattern must match, which are checked
+	// when it is loaded. They may be paths with "/", whose directories are
+	// the context of defaults that are templates.
+	Samples []string `json:"samples,omitempty"`
+}
+
+// PatternFile is the contents of a pattern library file.
+type PatternFile struct {
+	Patterns []PatternSpec `json:"patterns"`
+}
+
+// LoadPatterns adds the patterns in the library file at path, or in every
+// .json file in the directory at path, to those that are inferred from.
+// Every pattern is checked against its samples first; if any fails, none
+// of the patterns in that file are added.
+func LoadPatterns(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(files)
+	}
+
+	for _, v := range files {
+		if err := loadPatternFile(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadPatternFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var library PatternFile
+	if err := json.Unmarshal(data, &library); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, v := range library.Patterns {
+		if err := checkPattern(v); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, v := range library.Patterns {
+		if err := addPattern(v, path); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// checkPattern compiles spec and tests it against its samples, without
+// adding it.
+func checkPattern(spec PatternSpec) error {
+	var err error
+	switch spec.Kind {
+	case KindTV:
+		_, err = newPattern[Match](spec, "")
+	case KindAnime:
+		_, err = newPattern[Absolute](spec, "")
+	case KindDaily:
+		_, err = newPattern[Daily](spec, "")
+	case KindMovie:
+		_, err = newPattern[Movie](spec, "")
+	default:
+		err = fmt.Errorf("pattern %q: unknown kind %q", spec.Name, spec.Kind)
+	}
+	return err
+}
+
+// addPattern compiles spec and adds it to the patterns of its kind.
+func addPattern(spec PatternSpec, source string) error {
+	var err error
+	switch spec.Kind {
+	case KindTV:
+		patterns, err = appendPattern(patterns, spec, source)
+	case KindAnime:
+		absolutePatterns, err = appendPattern(absolutePatterns, spec, source)
+	case KindDaily:
+		dailyPatterns, err = appendPattern(dailyPatterns, spec, source)
+	case KindMovie:
+		moviePatterns, err = appendPattern(moviePatterns, spec, source)
+	default:
+		err = fmt.Errorf("pattern %q: unknown kind %q", spec.Name, spec.Kind)
+	}
+	return err
+}
+
+// appendPattern adds the pattern described by spec to list, which is kept
+// sorted by priority. Patterns of the same priority keep the order they
+// were added in.
+func appendPattern[T any](list []*Pattern[T], spec PatternSpec, source string) ([]*Pattern[T], error) {
+	pattern, err := newPattern[T](spec, source)
+	if err != nil {
+		return list, err
+	}
+	list = append(list, pattern)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Priority > list[j].Priority
+	})
+	return list, nil
+}
+
+func newPattern[T any](spec PatternSpec, source string) (*Pattern[T], error) {
+	if spec.Name == "" {
+		return nil, fmt.Errorf("pattern %q has no name", spec.Pattern)
+	}
+	re, err := regexps.CompileWithDefaults[T](spec.Pattern, spec.Defaults)
+	if err != nil {
+		return nil, fmt.Errorf("pattern %q: %w", spec.Name, err)
+	}
+	for _, v := range spec.Samples {
+		path := filepath.FromSlash(v)
+		if _, err := re.ParseStringContext(filepath.Base(path), dirContext(path)); err != nil {
+			return nil, fmt.Errorf("pattern %q does not match its sample %q", spec.Name, v)
+		}
+	}
+	return &Pattern[T]{
+		Regexp:   re,
+		Name:     spec.Name,
+		Priority: spec.Priority,
+		Source:   source,
+		Samples:  spec.Samples,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPatterns(t *testing.T) {
+	saved := append([]*Pattern[Match](nil), patterns...)
+	defer func() { patterns = saved }()
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "group.json"), []byte(`{
+		"patterns": [{
+			"name": "group-x",
+			"kind": "tv",
+			"priority": 10,
+			"pattern": "^GRP_(?P<name>[^_]+)_(?P<season>\\d+)(?P<episode>\\d\\d)\\.[^.]+$",
+			"samples": ["GRP_Show_102.mkv"]
+		}]
+	}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a library"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadPatterns(dir); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if patterns[0].Name != "group-x" || patterns[0].Source != filepath.Join(dir, "group.json") {
+		t.Fatalf("expected the loaded pattern first, got %q from %q", patterns[0].Name, patterns[0].Source)
+	}
+
+	pattern, err := InferPattern(wrapNamesInFS([]string{"GRP_Show_102.mkv", "GRP_Show_103.mkv"}), ".")
+	if err != nil {
+		t.Fatalf("infer: %v", err)
+	}
+	match := pattern.FindString("GRP_Show_103.mkv")
+	if match == nil || match.ShowName != "Show" || match.Season != 1 || match.Episode != 3 {
+		t.Errorf("unexpected match %+v", match)
+	}
+}
+
+func TestLoadPatternsSamples(t *testing.T) {
+	saved := append([]*Pattern[Match](nil), patterns...)
+	defer func() { patterns = saved }()
+
+	path := filepath.Join(t.TempDir(), "bad.json")
+	err := os.WriteFile(path, []byte(`{
+		"patterns": [
+			{"name": "ok", "kind": "tv", "pattern": "(?P<name>.+) (?P<season>\\d+)x(?P<episode>\\d+)", "samples": ["Show 1x02"]},
+			{"name": "wrong", "kind": "tv", "pattern": "(?P<name>.+) (?P<season>\\d+)x(?P<episode>\\d+)", "samples": ["Show S01E02"]}
+		]
+	}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = LoadPatterns(path)
+	if err == nil || !strings.Contains(err.Error(), `"wrong"`) {
+		t.Fatalf("expected the failing sample to be reported, got %v", err)
+	}
+	if len(patterns) != len(saved) {
+		t.Errorf("expected no patterns to be added, got %d", len(patterns)-len(saved))
+	}
+}
+
+func TestNumeralPatterns(t *testing.T) {
+	pattern, err := newPattern[Match](PatternSpec{
+		Name:    "words",
+		Kind:    KindTV,
+		Pattern: `^(?P<name>.+?) Season (?P<season>\w+) Part (?P<episode>\w+)\.[^.]+$`,
+		Samples: []string{"Show Season Two Part IV.mkv"},
+	}, "test")
+	if err != nil {
+		t.Fatalf("pattern: %v", err)
+	}
+
+	match := pattern.FindString("Show Season Two Part IV.mkv")
+	if match.ShowName != "Show" || match.Season != 2 || match.Episode != 4 {
+		t.Errorf("unexpected match %+v", match)
+	}
+}
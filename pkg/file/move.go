@@ -0,0 +1,107 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// move renames source to target, creating the parent directories of target.
+// If they are on different filesystems, source is copied to target, the
+// copy is checked against source, and only then is source removed.
+func move(source, target string) error {
+	err := os.MkdirAll(filepath.Dir(target), 0o755)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(source, target)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyFile(source, target); err != nil {
+		return fmt.Errorf("copy %q to %q: %w", source, target, err)
+	}
+	return os.Remove(source)
+}
+
+// copyFile copies source to target, keeping its mode and modification time.
+// The data is written to a temporary file next to target, which is verified
+// against source and then renamed to target, so target is left alone if the
+// copy fails.
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*")
+	if err != nil {
+		return err
+	}
+	temp := out.Name()
+	if err := writeCopy(out, in, info); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	if err := os.Rename(temp, target); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
+
+// writeCopy copies in to out, which it closes, and checks that the data
+// written matches in. out is given the mode and modification time in info.
+func writeCopy(out *os.File, in io.Reader, info os.FileInfo) error {
+	err := out.Chmod(info.Mode().Perm())
+	sourceHash := sha256.New()
+	if err == nil {
+		_, err = io.Copy(out, io.TeeReader(in, sourceHash))
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	targetHash, err := hashFile(out.Name())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sourceHash.Sum(nil), targetHash) {
+		return errors.New("copy does not match source")
+	}
+
+	return os.Chtimes(out.Name(), info.ModTime(), info.ModTime())
+}
+
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,99 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.mkv")
+	target := filepath.Join(dir, "target.mkv")
+
+	if err := os.WriteFile(source, []byte("episode"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(source, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyFile(source, target); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+
+	b, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "episode" {
+		t.Errorf("wrong contents, got: %q", b)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("wrong mtime, got: %v", info.ModTime())
+	}
+}
+
+func TestCopyFileKeepsTarget(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.mkv")
+	if err := os.WriteFile(target, []byte("old"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyFile(filepath.Join(dir, "missing.mkv"), target); err == nil {
+		t.Fatal("expected an error copying a missing file")
+	}
+
+	b, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("target removed: %v", err)
+	}
+	if string(b) != "old" {
+		t.Errorf("target changed, got: %q", b)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the target to be left, got %d files", len(entries))
+	}
+}
+
+func TestCopyFileOverwrites(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.mkv")
+	target := filepath.Join(dir, "target.mkv")
+	if err := os.WriteFile(source, []byte("new"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("old contents"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyFile(source, target); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+
+	b, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "new" {
+		t.Errorf("wrong contents, got: %q", b)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the mode of the source, got %v", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,231 @@
+package file
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Normalizer cleans up the names and titles captured from file names before
+// they are given to the output template. Each step can be turned on on its
+// own; they are applied in the order of the fields.
+type Normalizer struct {
+	// StripTags removes tags in square or curly brackets, such as
+	// "[SubGroup]" or "{tvdb-1234}". Parentheses are kept, as they usually
+	// hold a year that tells shows of the same name apart.
+	StripTags bool
+	// Undot replaces the dots and underscores used instead of spaces with
+	// spaces.
+	Undot bool
+	// StripRelease removes release tokens, such as "PROPER", "1080p" or
+	// "x264", and everything after the first of them, which is usually the
+	// rest of the release name. Tokens that are also words, like "PROPER",
+	// only count in upper case, and the first word is always kept.
+	StripRelease bool
+	// Apostrophes restores the apostrophes of the words in Dictionary and
+	// the included contractions, e.g. "Dont" becomes "Don't".
+	Apostrophes bool
+	// TitleCase capitalizes words other than small ones like "of" and "the",
+	// leaving words that are already in mixed case, like "McDonald", alone.
+	TitleCase bool
+	// Dictionary maps lower case words to how they are written with their
+	// apostrophes, besides the included contractions.
+	Dictionary map[string]string
+}
+
+// NormalizeSteps are the names of the steps of a Normalizer, in the order
+// they are applied.
+var NormalizeSteps = []string{"tags", "undot", "release", "apostrophes", "title"}
+
+// ParseNormalizer returns the Normalizer with the comma-separated steps in
+// s turned on, or every step for "all". It returns nil for "".
+func ParseNormalizer(s string) (*Normalizer, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if s == "all" {
+		s = strings.Join(NormalizeSteps, ",")
+	}
+	n := new(Normalizer)
+	for _, v := range strings.Split(s, ",") {
+		step := n.step(strings.TrimSpace(v))
+		if step == nil {
+			return nil, fmt.Errorf("unknown normalize step %q", v)
+		}
+		*step = true
+	}
+	return n, nil
+}
+
+// step returns the field that turns on the step called name, or nil.
+func (n *Normalizer) step(name string) *bool {
+	switch name {
+	case "tags":
+		return &n.StripTags
+	case "undot":
+		return &n.Undot
+	case "release":
+		return &n.StripRelease
+	case "apostrophes":
+		return &n.Apostrophes
+	case "title":
+		return &n.TitleCase
+	}
+	return nil
+}
+
+// Steps returns the names of the steps that are turned on.
+func (n *Normalizer) Steps() []string {
+	var steps []string
+	for _, v := range NormalizeSteps {
+		if *n.step(v) {
+			steps = append(steps, v)
+		}
+	}
+	return steps
+}
+
+// Apply normalizes the names and titles of match, a *Match, *Daily or
+// *Movie, in place.
+func (n *Normalizer) Apply(match any) {
+	switch m := match.(type) {
+	case *Match:
+		m.ShowName = n.String(m.ShowName)
+		m.Title = n.String(m.Title)
+	case *Daily:
+		m.ShowName = n.String(m.ShowName)
+		m.Title = n.String(m.Title)
+	case *Movie:
+		m.Title = n.String(m.Title)
+	}
+}
+
+var (
+	bracketedTag  = regexp.MustCompile(`\[[^\]]*\]|\{[^}]*\}`)
+	releaseToken  = regexp.MustCompile(`(?i)(?:^|[\s.\-_])(?:hdtv|pdtv|web-?dl|webrip|web-?rip|bluray|blu-ray|brrip|bdrip|dvdrip|hdrip|x26[45]|h[\s.]?26[45]|hevc|xvid|divx|ac3|e-?ac-?3|ddp?5[\s.]?1|10bit|4k|\d{3,4}[pi])(?:$|[\s.\-_\[(])`)
+	releaseWord   = regexp.MustCompile(`(?:^|[\s.\-_])(?:PROPER|REPACK|RERIP|INTERNAL|REMUX|AVC|AAC|DTS|FLAC|HDR|UHD)(?:$|[\s.\-_\[(])`)
+	smallWords    = []string{"a", "an", "and", "as", "at", "but", "by", "for", "in", "nor", "of", "on", "or", "the", "to", "vs", "with"}
+	romanNumeral  = regexp.MustCompile(`^(?i)(?:x{0,3})(?:ix|iv|v?i{0,3})$`)
+	contractions  = map[string]string{}
+	contractedRaw = []string{
+		"ain't", "aren't", "can't", "couldn't", "didn't", "doesn't", "don't", "hadn't", "hasn't", "haven't",
+		"isn't", "mustn't", "shouldn't", "wasn't", "weren't", "won't", "wouldn't",
+		"i'm", "i've", "you're", "you've", "you'll", "you'd", "they're", "they've", "they'll",
+		"we've", "that's", "what's", "where's", "there's", "here's", "who's", "how's", "y'all",
+	}
+)
+
+func init() {
+	for _, v := range contractedRaw {
+		contractions[strings.ReplaceAll(v, "'", "")] = v
+	}
+}
+
+// String normalizes a single name or title. If nothing would be left of s,
+// it is returned as it is.
+func (n *Normalizer) String(s string) string {
+	input := s
+	if n.StripTags {
+		s = bracketedTag.ReplaceAllString(s, " ")
+	}
+	if n.Undot {
+		s = undot(s)
+	}
+	if n.StripRelease {
+		s = stripRelease(s)
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if n.Apostrophes {
+		s = n.restoreApostrophes(s)
+	}
+	if n.TitleCase {
+		s = smartTitle(s)
+	}
+	s = strings.TrimRight(s, " -_.")
+	if s == "" {
+		return input
+	}
+	return s
+}
+
+// stripRelease cuts s at the first release token that comes after its first
+// word.
+func stripRelease(s string) string {
+	end := len(s)
+	for _, re := range []*regexp.Regexp{releaseToken, releaseWord} {
+		for _, loc := range re.FindAllStringIndex(s, -1) {
+			if strings.Trim(s[:loc[0]], " .-_") != "" {
+				if loc[0] < end {
+					end = loc[0]
+				}
+				break
+			}
+		}
+	}
+	return s[:end]
+}
+
+// restoreApostrophes puts the apostrophes back into the words of s that are
+// in the dictionary or are contractions, keeping the case of their first
+// letter.
+func (n *Normalizer) restoreApostrophes(s string) string {
+	words := strings.Split(s, " ")
+	for i, v := range words {
+		lower := strings.ToLower(v)
+		restored, ok := n.Dictionary[lower]
+		if !ok {
+			restored, ok = contractions[lower]
+		}
+		if !ok {
+			continue
+		}
+		if lower == "im" || lower == "ive" {
+			restored = "I" + restored[1:]
+		} else if r, _ := utf8.DecodeRuneInString(v); unicode.IsUpper(r) {
+			restored = capitalize(restored)
+		}
+		words[i] = restored
+	}
+	return strings.Join(words, " ")
+}
+
+// smartTitle upper-cases the first letter of the words in s, except small
+// words that aren't first or last. If s is all in one case every word is
+// cased; otherwise words that already have upper case letters, such as
+// acronyms, are left alone, as are roman numerals in upper case.
+func smartTitle(s string) string {
+	oneCase := s == strings.ToLower(s) || s == strings.ToUpper(s)
+	words := strings.Split(s, " ")
+	for i, v := range words {
+		lower := strings.ToLower(v)
+		switch {
+		case v == "":
+		case v == strings.ToUpper(v) && romanNumeral.MatchString(v):
+		case i != 0 && i != len(words)-1 && isSmallWord(lower):
+			words[i] = lower
+		case oneCase || v == lower:
+			words[i] = capitalize(lower)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func isSmallWord(word string) bool {
+	for _, v := range smallWords {
+		if v == word {
+			return true
+		}
+	}
+	return false
+}
+
+// capitalize upper-cases the first letter of s.
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
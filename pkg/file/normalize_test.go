@@ -0,0 +1,79 @@
+package file
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizer(t *testing.T) {
+	all, err := ParseNormalizer("all")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	all.Dictionary = map[string]string{"greys": "grey's"}
+
+	tests := []struct {
+		normalizer *Normalizer
+		input      string
+		expected   string
+	}{
+		{all, "Dont", "Don't"},
+		{all, "you.cant.stop.the.music", "You Can't Stop the Music"},
+		{all, "[SubGroup] Show_Name", "Show Name"},
+		{all, "The.Title.PROPER.720p.WEB-DL.x264-GRP", "The Title"},
+		{all, "Greys.Anatomy", "Grey's Anatomy"},
+		{all, "THE LORD OF THE RINGS", "The Lord of the Rings"},
+		{all, "Rocky II", "Rocky II"},
+		{all, "Doctor Who (2005) {tvdb-78804}", "Doctor Who (2005)"},
+		{all, "The FBI Files", "The FBI Files"},
+		{all, "Stuck in the Middle With", "Stuck in the Middle With"},
+		{all, "Charlottes.Web", "Charlottes Web"},
+		{&Normalizer{Undot: true}, "the.title.1080p", "the title 1080p"},
+		{&Normalizer{StripRelease: true}, "Title.1080p.x264", "Title"},
+		{&Normalizer{StripRelease: true}, "Internal Affairs", "Internal Affairs"},
+		{&Normalizer{StripRelease: true}, "A Proper Send-Off", "A Proper Send-Off"},
+		{&Normalizer{StripRelease: true}, "Hdr Story", "Hdr Story"},
+		{&Normalizer{StripRelease: true}, "1080p", "1080p"},
+		{&Normalizer{StripRelease: true}, "Title REPACK 1080p", "Title"},
+		{&Normalizer{TitleCase: true}, "a tale of two cities", "A Tale of Two Cities"},
+		{&Normalizer{}, "Some.Title_", "Some.Title"},
+	}
+
+	for _, test := range tests {
+		if actual := test.normalizer.String(test.input); actual != test.expected {
+			t.Errorf("%q with %v: expected %q, got %q", test.input, test.normalizer.Steps(), test.expected, actual)
+		}
+	}
+}
+
+func TestParseNormalizer(t *testing.T) {
+	n, err := ParseNormalizer("title, undot")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if steps := n.Steps(); !reflect.DeepEqual(steps, []string{"undot", "title"}) {
+		t.Errorf("expected the steps in order, got %v", steps)
+	}
+
+	if n, err := ParseNormalizer(""); n != nil || err != nil {
+		t.Errorf("expected no normalizer, got %v, %v", n, err)
+	}
+	if _, err := ParseNormalizer("undot,shout"); err == nil {
+		t.Error("expected an error for an unknown step")
+	}
+}
+
+func TestNormalizeMatch(t *testing.T) {
+	n := &Normalizer{Undot: true, Apostrophes: true}
+	match := &Match{ShowName: "You", Season: 2, Episode: 5, Title: "Dont"}
+	n.Apply(match)
+	if match.Title != "Don't" {
+		t.Errorf("expected the title to be normalized, got %q", match.Title)
+	}
+
+	movie := &Movie{Title: "The.Movie", Year: 2001}
+	n.Apply(movie)
+	if movie.Title != "The Movie" {
+		t.Errorf("expected the title to be normalized, got %q", movie.Title)
+	}
+}
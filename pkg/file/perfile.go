@@ -0,0 +1,107 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elliotcubit/renamer/pkg/regexps"
+)
+
+// PerFile is a Finder that matches every file name against a list of
+// patterns on its own, using the first that matches, so that a directory
+// of files from different sources needn't match a single pattern.
+type PerFile[T any] struct {
+	Patterns []*Pattern[T]
+}
+
+// FindPattern returns the first pattern that matches s, and what it
+// matched, or nil if none does.
+func (p *PerFile[T]) FindPattern(s string) (*Pattern[T], *T) {
+	pattern, match, _, _ := p.findPattern(s, nil)
+	return pattern, match
+}
+
+// findPattern returns the first pattern that matches s with context, what
+// it matched, and its groups. If none do, the error is that of the first
+// pattern that matched s but couldn't convert it, if any.
+func (p *PerFile[T]) findPattern(s string, context map[string]string) (*Pattern[T], *T, map[string]string, error) {
+	var firstErr error
+	for _, v := range p.Patterns {
+		match, groups, err := v.ParseStringGroups(s, context)
+		if err == nil {
+			return v, match, groups, nil
+		}
+		var noMatch *regexps.NoMatchFoundError
+		if firstErr == nil && !errors.As(err, &noMatch) {
+			firstErr = fmt.Errorf("%s: %w", v.Name, err)
+		}
+	}
+	if firstErr == nil {
+		firstErr = &regexps.NoMatchFoundError{}
+	}
+	return nil, nil, nil, firstErr
+}
+
+// ParseString returns what the first pattern that matches s matched. If
+// none do, the error is that of the first pattern that matched s but
+// couldn't convert it, if any.
+func (p *PerFile[T]) ParseString(s string) (*T, error) {
+	return p.ParseStringContext(s, nil)
+}
+
+// ParseStringContext is ParseString with the context of s for the defaults
+// of the patterns.
+func (p *PerFile[T]) ParseStringContext(s string, context map[string]string) (*T, error) {
+	_, match, _, err := p.findPattern(s, context)
+	return match, err
+}
+
+func (p *PerFile[T]) FindString(s string) *T {
+	_, match := p.FindPattern(s)
+	return match
+}
+
+// MatchingPattern returns the name of the first pattern that matches s with
+// context, or "" if none does.
+func (p *PerFile[T]) MatchingPattern(s string, context map[string]string) string {
+	if pattern, _, _, _ := p.findPattern(s, context); pattern != nil {
+		return pattern.Name
+	}
+	return ""
+}
+
+func (p *PerFile[T]) find(s string, context map[string]string) (*T, string, map[string]string, error) {
+	pattern, match, groups, err := p.findPattern(s, context)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return match, pattern.Name, groups, nil
+}
+
+func (p *PerFile[T]) String() string {
+	names := make([]string, len(p.Patterns))
+	for i, v := range p.Patterns {
+		names[i] = v.Name
+	}
+	return "per-file: " + strings.Join(names, ", ")
+}
+
+// PatternsFor returns the patterns that files of the kind held by T are
+// inferred with, in the order they are tried: the included patterns, and
+// any loaded with LoadPatterns.
+func PatternsFor[T any]() []*Pattern[T] {
+	var list any
+	switch any(*new(T)).(type) {
+	case Match:
+		list = patterns
+	case Absolute:
+		list = absolutePatterns
+	case Daily:
+		list = dailyPatterns
+	case Movie:
+		list = moviePatterns
+	}
+	ret, _ := list.([]*Pattern[T])
+	return append([]*Pattern[T](nil), ret...)
+}
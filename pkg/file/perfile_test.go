@@ -0,0 +1,102 @@
+package file
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elliotcubit/renamer/pkg/regexps"
+)
+
+func TestPerFile(t *testing.T) {
+	fs := wrapNamesInFS([]string{
+		"House - [4x04] - Guardian Angels.mp4",
+		"You.S02E05.Dont.720p.mkv",
+		"random.mkv",
+		"readme.txt",
+	})
+
+	plan, err := NewPlan[Match](fs, ".", &PerFile[Match]{Patterns: PatternsFor[Match]()}, Options{
+		Template: DefaultTemplates[KindTV],
+	})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+
+	expected := map[string]string{
+		"House - [4x04] - Guardian Angels.mp4": "tv-bracketed",
+		"You.S02E05.Dont.720p.mkv":             "tv-dotted",
+	}
+	if len(plan.Renames) != len(expected) {
+		t.Fatalf("expected %d renames, got %d", len(expected), len(plan.Renames))
+	}
+	for _, v := range plan.Renames {
+		if name := filepath.Base(v.Source); v.Pattern != expected[name] {
+			t.Errorf("%q: expected pattern %q, got %q", name, expected[name], v.Pattern)
+		}
+	}
+
+	if len(plan.Skipped) != 1 || filepath.Base(plan.Skipped[0].File) != "random.mkv" {
+		t.Errorf("expected only random.mkv to be skipped, got %v", plan.Skipped)
+	}
+}
+
+func TestSkipReasons(t *testing.T) {
+	fs := wrapNamesInFS([]string{
+		"Show.S01E02.mkv",
+		"Show.S1aE03.mkv",
+		"Other.mkv",
+	})
+	pattern := regexps.MustCompile[Match](`^(?P<name>[^.]+)\.S(?P<season>\w+)E(?P<episode>\d+)`)
+
+	plan, err := NewPlan[Match](fs, ".", pattern, Options{Template: DefaultTemplates[KindTV]})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(plan.Renames) != 1 {
+		t.Errorf("expected 1 rename, got %d", len(plan.Renames))
+	}
+
+	reasons := make(map[string]string)
+	for _, v := range plan.Skipped {
+		reasons[filepath.Base(v.File)] = v.Reason
+	}
+	if reasons["Other.mkv"] != "no pattern matched" {
+		t.Errorf("unexpected reason for Other.mkv: %q", reasons["Other.mkv"])
+	}
+	if !strings.Contains(reasons["Show.S1aE03.mkv"], `"season"`) {
+		t.Errorf("expected a parse error for Show.S1aE03.mkv, got %q", reasons["Show.S1aE03.mkv"])
+	}
+}
+
+func TestPerFileGroups(t *testing.T) {
+	fs := wrapNamesInFS([]string{
+		"Show.S01E02.1080p.mkv",
+		"Show - 1x03 [720p].mkv",
+	})
+	finder := &PerFile[Match]{Patterns: []*Pattern[Match]{
+		namedPattern("dotted", 0, `^(?P<name>[^.]+)\.S(?P<season>\d+)E(?P<episode>\d+)\.(?P<quality>\d+p)`),
+		namedPattern("dashed", 0, `^(?P<name>.+?) - (?P<season>\d+)x(?P<episode>\d+) \[(?P<quality>\d+p)\]`),
+	}}
+
+	renames, err := PlanRenames[Match](fs, ".", finder, Options{
+		Template: `{{ .ShowName }} - s{{ pad 2 .Season }}{{ .Episodes }} [{{ group "quality" }}]`,
+	})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+
+	expected := map[string][2]string{
+		"Show.S01E02.1080p.mkv":  {"Show - s01e02 [1080p].mkv", "dotted"},
+		"Show - 1x03 [720p].mkv": {"Show - s01e03 [720p].mkv", "dashed"},
+	}
+	if len(renames) != len(expected) {
+		t.Fatalf("expected %d renames, got %d", len(expected), len(renames))
+	}
+	for _, v := range renames {
+		want := expected[filepath.Base(v.Source)]
+		if filepath.Base(v.Target) != want[0] || v.Pattern != want[1] {
+			t.Errorf("%q: expected %q from %s, got %q from %s", filepath.Base(v.Source), want[0], want[1], filepath.Base(v.Target), v.Pattern)
+		}
+	}
+}
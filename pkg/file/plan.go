@@ -0,0 +1,416 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Plan is every rename that a run will perform. It can be written out,
+// reviewed, and applied later with Apply.
+type Plan struct {
+	Kind      Kind           `json:"kind"`
+	Dir       string         `json:"dir"`
+	Dest      string         `json:"dest,omitempty"`
+	Pattern   string         `json:"pattern"`
+	Template  string         `json:"template"`
+	Policy    ConflictPolicy `json:"policy"`
+	Sanitize  string         `json:"sanitize,omitempty"`
+	MatchPath bool           `json:"match_path,omitempty"`
+	Normalize []string       `json:"normalize,omitempty"`
+	Renames   []Rename       `json:"renames"`
+	Conflicts []Conflict     `json:"conflicts,omitempty"`
+	// Skipped are the files that the pattern didn't match, which are left
+	// alone.
+	Skipped []Skip `json:"skipped,omitempty"`
+}
+
+// Skip is a file that is left alone, because it didn't match or what it
+// matched couldn't be used.
+type Skip struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// Rename is a single planned rename of the file at Source to Target.
+// Source includes the directory being renamed in, and Target includes
+// either that directory or the destination root. Size and ModTime
+// describe Source when the plan was made. Match holds the fields captured
+// from the file name, a *Match or *Movie depending on the kind of the plan;
+// in a plan read back with ReadPlan it is a generic JSON object.
+type Rename struct {
+	Source  string    `json:"source"`
+	Target  string    `json:"target"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Match   any       `json:"match"`
+	// Pattern is the name of the pattern that matched Source, when each
+	// file is matched on its own.
+	Pattern string `json:"pattern,omitempty"`
+	// Rendered is the output of the template, if it had to be sanitized
+	// to get Target.
+	Rendered string `json:"rendered,omitempty"`
+	// Sidecars are moved along with Source, and named after Target.
+	Sidecars []Sidecar `json:"sidecars,omitempty"`
+}
+
+// Options controls where the files matched by a pattern are renamed to.
+type Options struct {
+	// Template is the output template, which may contain path separators.
+	Template string
+	// Policy decides what happens when a target is already taken.
+	Policy ConflictPolicy
+	// Dest is the library root that targets are relative to. If empty,
+	// files are renamed within the directory they are already in.
+	Dest string
+	// Sanitize cleans up the output of the template for the filesystem
+	// it is written to. If nil, the output is used as is.
+	Sanitize *SanitizeProfile
+	// MatchPath matches the pattern against the path of each file relative
+	// to the directory, with "/" between its parts, instead of its name.
+	MatchPath bool
+	// Normalize cleans up the names and titles captured from each file
+	// before the template is applied. If nil, they are used as is.
+	Normalize *Normalizer
+}
+
+// NewPlan plans the renames of every file in fsys that matches pattern,
+// and resolves any conflicts between them with the policy in opts.
+func NewPlan[T Media](
+	fsys fs.FS,
+	dir string,
+	pattern Finder[T],
+	opts Options,
+) (*Plan, error) {
+	// Plans are applied later, possibly from another directory
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Dest != "" {
+		if opts.Dest, err = filepath.Abs(opts.Dest); err != nil {
+			return nil, err
+		}
+	}
+	renames, skipped, err := planRenames(fsys, dir, pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	renames, conflicts, err := ResolveConflicts(renames, opts.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Kind:      KindOf[T](),
+		Dir:       dir,
+		Dest:      opts.Dest,
+		Pattern:   pattern.String(),
+		Template:  opts.Template,
+		Policy:    opts.Policy,
+		MatchPath: opts.MatchPath,
+		Renames:   renames,
+		Conflicts: conflicts,
+		Skipped:   skipped,
+	}
+	if opts.Sanitize != nil {
+		plan.Sanitize = opts.Sanitize.Name
+	}
+	if opts.Normalize != nil {
+		plan.Normalize = opts.Normalize.Steps()
+	}
+	return plan, nil
+}
+
+// ReadPlan decodes a plan written by Plan.Write.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	plan := new(Plan)
+	if err := json.NewDecoder(r).Decode(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (p *Plan) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(p)
+}
+
+// Print describes the plan in a human-readable form.
+func (p *Plan) Print(w io.Writer) {
+	fmt.Fprintf(w, "In %q, would:\n", p.Dir)
+
+	for _, v := range p.Conflicts {
+		v.Source, v.Target, v.Other, v.Resolution = p.rel(v.Source), p.rel(v.Target), p.rel(v.Other), p.rel(v.Resolution)
+		if v.Resolution == "" {
+			fmt.Fprintf(w, "  Skip %s\n", v)
+		} else if v.Resolution == v.Target {
+			fmt.Fprintf(w, "  Overwrite %s\n", v)
+		} else {
+			fmt.Fprintf(w, "  Use %q for %s\n", v.Resolution, v)
+		}
+	}
+
+	for _, v := range p.Renames {
+		if v.Pattern != "" {
+			fmt.Fprintf(w, "  Rename %q -> %q (%s)\n", p.rel(v.Source), p.rel(v.Target), v.Pattern)
+		} else {
+			fmt.Fprintf(w, "  Rename %q -> %q\n", p.rel(v.Source), p.rel(v.Target))
+		}
+		if v.Rendered != "" {
+			fmt.Fprintf(w, "    sanitized from %q\n", v.Rendered)
+		}
+		for _, sidecar := range v.Sidecars {
+			fmt.Fprintf(w, "    with %q -> %q\n", p.rel(sidecar.Source), p.rel(sidecar.Target))
+		}
+	}
+	p.PrintSkipped(w)
+	fmt.Fprintf(w, "  Would rename %d files\n", len(p.Renames))
+}
+
+// PrintSkipped lists the files that are left alone, and why.
+func (p *Plan) PrintSkipped(w io.Writer) {
+	for _, v := range p.Skipped {
+		fmt.Fprintf(w, "  Leave %q: %s\n", p.rel(v.File), v.Reason)
+	}
+}
+
+// rel shortens path to be relative to the directory of the plan for
+// printing, unless it is outside of it.
+func (p *Plan) rel(path string) string {
+	if path == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	rel, err := filepath.Rel(p.Dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
+
+// Verify checks that the plan can still be applied as it was made: every
+// source is unchanged, and no target has appeared since, unless the plan
+// overwrites targets anyway.
+func (p *Plan) Verify() error {
+	for _, v := range p.Renames {
+		if err := p.verify(v.Source, v.Target, v.Size, v.ModTime); err != nil {
+			return err
+		}
+		for _, sidecar := range v.Sidecars {
+			if err := p.verify(sidecar.Source, sidecar.Target, sidecar.Size, sidecar.ModTime); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Plan) verify(source, target string, size int64, modTime time.Time) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if info.Size() != size || !info.ModTime().Equal(modTime) {
+		return fmt.Errorf("%q has changed since the plan was made", source)
+	}
+	if p.Policy != ConflictOverwrite && targetExists(source, target) {
+		return fmt.Errorf("%q already exists", target)
+	}
+	return nil
+}
+
+// Apply verifies the plan and performs its renames, recording each in
+// journal if it is not nil.
+func (p *Plan) Apply(journal *Journal) error {
+	if err := p.Verify(); err != nil {
+		return err
+	}
+	return ApplyRenames(p.Renames, journal)
+}
+
+// PlanRenames works out the target of every file in fsys that matches
+// pattern, without touching anything on disk. Sidecar files are planned
+// along with the video they belong to rather than matched on their own.
+// Files that are already named correctly are left out.
+func PlanRenames[T Media](
+	fsys fs.FS,
+	dir string,
+	pattern Finder[T],
+	opts Options,
+) ([]Rename, error) {
+	renames, _, err := planRenames(fsys, dir, pattern, opts)
+	return renames, err
+}
+
+// planRenames is PlanRenames, also returning the files in dir that were
+// skipped. Files that are okay not to match are left out of those.
+func planRenames[T Media](
+	fsys fs.FS,
+	dir string,
+	pattern Finder[T],
+	opts Options,
+) ([]Rename, []Skip, error) {
+	// Sources and targets are absolute, so that they still refer to the
+	// same files wherever a plan is applied from
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Dest != "" {
+		if opts.Dest, err = filepath.Abs(opts.Dest); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	tmpl, err := parseTemplate(opts.Template)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad template: %w", err)
+	}
+
+	paths, err := listFiles(fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sidecars := findSidecars(paths)
+	isSidecar := make(map[string]bool)
+	for _, v := range sidecars {
+		for _, sidecar := range v {
+			isSidecar[sidecar] = true
+		}
+	}
+
+	var renames []Rename
+	var skipped []Skip
+	buf := new(strings.Builder)
+
+	for _, path := range paths {
+		if isSidecar[path] {
+			continue
+		}
+
+		dir2, file := filepath.Split(path)
+		ext := filepath.Ext(file)
+
+		subject := file
+		if opts.MatchPath {
+			subject = path
+		}
+		context := PathContext(filepath.Join(dir, path))
+		match, patternName, groups, err := find(pattern, subject, context)
+		if err != nil {
+			if !isOkay(file) {
+				skipped = append(skipped, Skip{File: filepath.Join(dir, path), Reason: skipReason(err)})
+			}
+			continue
+		}
+		if opts.Normalize != nil {
+			opts.Normalize.Apply(match)
+		}
+
+		tmpl.Funcs(template.FuncMap{"group": func(name string) string {
+			return groups[name]
+		}})
+		buf.Reset()
+		err = tmpl.Execute(buf, match)
+		if err != nil {
+			return nil, nil, fmt.Errorf("apply template: %w", err)
+		}
+
+		stem := strings.TrimSuffix(file, ext)
+		var sidecarFiles []Sidecar
+		reserve := 0
+		for _, v := range sidecars[path] {
+			info, err := fs.Stat(fsys, v)
+			if err != nil {
+				return nil, nil, err
+			}
+			suffix := sidecarSuffix(strings.TrimPrefix(filepath.Base(v), stem))
+			sidecarFiles = append(sidecarFiles, Sidecar{
+				Source:  filepath.Join(dir, v),
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+				suffix:  suffix,
+			})
+			if n := len(suffix) - len(ext); n > reserve {
+				reserve = n
+			}
+		}
+
+		rendered := filepath.FromSlash(buf.String())
+		newFile := rendered + ext
+		if opts.Sanitize != nil {
+			newFile = opts.Sanitize.Path(rendered, ext, reserve)
+		}
+
+		root := filepath.Join(dir, dir2)
+		if opts.Dest != "" {
+			root = opts.Dest
+		}
+		target := filepath.Join(root, newFile)
+		if !isWithin(root, target) {
+			return nil, nil, fmt.Errorf("template output %q for %q is outside of %q", newFile, path, root)
+		}
+
+		info, err := fs.Stat(fsys, path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rename := Rename{
+			Source:   filepath.Join(dir, path),
+			Target:   target,
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+			Match:    match,
+			Pattern:  patternName,
+			Sidecars: sidecarFiles,
+		}
+		if newFile != filepath.Clean(rendered+ext) {
+			rename.Rendered = rendered
+		}
+		rename.setSidecarTargets()
+
+		if !rename.unchanged() {
+			renames = append(renames, rename)
+		}
+	}
+
+	return renames, skipped, nil
+}
+
+// setSidecarTargets names the sidecars of r after its target.
+func (r *Rename) setSidecarTargets() {
+	stem := strings.TrimSuffix(r.Target, filepath.Ext(r.Target))
+	for i := range r.Sidecars {
+		r.Sidecars[i].Target = stem + r.Sidecars[i].suffix
+	}
+}
+
+// unchanged reports whether r and its sidecars are already named correctly.
+func (r *Rename) unchanged() bool {
+	if r.Source != r.Target {
+		return false
+	}
+	for _, v := range r.Sidecars {
+		if v.Source != v.Target {
+			return false
+		}
+	}
+	return true
+}
+
+// isWithin reports whether path is root or inside of it.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
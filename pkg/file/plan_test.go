@@ -0,0 +1,243 @@
+package file
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elliotcubit/renamer/pkg/regexps"
+)
+
+func TestPlanRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "House - [4x04] - Guardian Angels.mp4")
+	if err := os.WriteFile(source, []byte("episode"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := NewPlan[Match](os.DirFS(dir), dir, patterns[0], Options{
+		Template: "{{ .ShowName }} s{{ .Season }}e{{ .Episode }} - {{ .Title }}",
+		Policy:   ConflictAbort,
+	})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := plan.Write(buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	read, err := ReadPlan(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	if len(read.Renames) != 1 {
+		t.Fatalf("expected 1 rename, got %d", len(read.Renames))
+	}
+	rename := read.Renames[0]
+	if rename.Target != filepath.Join(dir, "House s4e4 - Guardian Angels.mp4") {
+		t.Errorf("wrong target, got: %q", rename.Target)
+	}
+	if match, ok := rename.Match.(map[string]any); !ok || match["title"] != "Guardian Angels" {
+		t.Errorf("wrong match, got: %+v", rename.Match)
+	}
+	if err := read.Verify(); err != nil {
+		t.Errorf("verify unchanged source: %v", err)
+	}
+
+	if err := os.WriteFile(source, []byte("a different episode"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := read.Apply(nil); err == nil {
+		t.Error("applied plan with changed source")
+	}
+}
+
+func TestPlanDest(t *testing.T) {
+	dir := t.TempDir()
+	dest := t.TempDir()
+	for _, v := range []string{"You.S02E05.Dont.720p.mkv", "The.Office.S01E01.Pilot.Episode.mkv"} {
+		if err := os.WriteFile(filepath.Join(dir, v), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	renames, err := PlanRenames[Match](os.DirFS(dir), dir, patterns[1], Options{
+		Template: LibraryTemplates[KindTV],
+		Dest:     dest,
+	})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	expected := map[string]string{
+		"You.S02E05.Dont.720p.mkv":            filepath.Join(dest, "You", "Season 02", "You - s02e05 - Dont.mkv"),
+		"The.Office.S01E01.Pilot.Episode.mkv": filepath.Join(dest, "The Office", "Season 01", "The Office - s01e01 - Pilot Episode.mkv"),
+	}
+	if len(renames) != len(expected) {
+		t.Fatalf("expected %d renames, got %d", len(expected), len(renames))
+	}
+	for _, v := range renames {
+		if name := filepath.Base(v.Source); v.Target != expected[name] {
+			t.Errorf("%q: wrong target, got: %q", name, v.Target)
+		}
+	}
+
+	_, err = PlanRenames[Match](os.DirFS(dir), dir, patterns[1], Options{
+		Template: "../{{ .ShowName }}",
+		Dest:     dest,
+	})
+	if err == nil {
+		t.Error("planned a target outside of the destination")
+	}
+}
+
+func TestPlanPathContext(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Show")
+	for _, v := range []string{"Season 3/01 - Pilot.mkv", "Specials/02 - Extra.mkv"} {
+		path := filepath.Join(dir, filepath.FromSlash(v))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pattern := regexps.MustCompileWithDefaults[Match](`^(?P<episode>\d+) - (?P<title>.+)\.mkv$`, map[string]string{
+		"name":   "{{ .grandparent }}",
+		"season": "{{ or (number .parent) 0 }}",
+	})
+	renames, err := PlanRenames[Match](os.DirFS(dir), dir, pattern, Options{
+		Template: "{{ .ShowName }} - s{{ pad 2 .Season }}{{ .Episodes }}",
+	})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "Season 3", "Show - s03e01.mkv"),
+		filepath.Join(dir, "Specials", "Show - s00e02.mkv"),
+	}
+	if len(renames) != len(expected) {
+		t.Fatalf("expected %d renames, got %d", len(expected), len(renames))
+	}
+	for i, v := range expected {
+		if renames[i].Target != v {
+			t.Errorf("expected %q, got %q", v, renames[i].Target)
+		}
+	}
+}
+
+func TestPathContext(t *testing.T) {
+	context := dirContext(filepath.FromSlash("Show/Season 3/01.mkv"))
+	if context["parent"] != "Season 3" || context["grandparent"] != "Show" {
+		t.Errorf("wrong context, got: %v", context)
+	}
+	context = dirContext("01.mkv")
+	if context["parent"] != "" || context["grandparent"] != "" {
+		t.Errorf("expected no context, got: %v", context)
+	}
+}
+
+func TestPlanMatchPath(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "Show.S01.1080p", "01.mkv")
+	if err := os.MkdirAll(filepath.Dir(source), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(source, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pattern := regexps.MustCompile[Match](`^(?P<name>[^/]+?)\.S(?P<season>\d+)\.(?P<quality>\d+p)/(?P<episode>\d+)\.mkv$`)
+	opts := Options{
+		Template:  `{{ .ShowName }} - s{{ pad 2 .Season }}{{ .Episodes }} [{{ group "quality" }}]`,
+		MatchPath: true,
+	}
+	plan, err := NewPlan[Match](os.DirFS(dir), dir, pattern, opts)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(plan.Renames) != 1 || !plan.MatchPath {
+		t.Fatalf("expected 1 rename matching paths, got %+v", plan)
+	}
+	expected := filepath.Join(dir, "Show.S01.1080p", "Show - s01e01 [1080p].mkv")
+	if plan.Renames[0].Target != expected {
+		t.Errorf("expected %q, got %q", expected, plan.Renames[0].Target)
+	}
+
+	opts.MatchPath = false
+	plan, err = NewPlan[Match](os.DirFS(dir), dir, pattern, opts)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(plan.Renames) != 0 || len(plan.Skipped) != 1 {
+		t.Errorf("expected the file name alone not to match, got %+v", plan)
+	}
+}
+
+func TestPlanApplyElsewhere(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "show"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(root, "show", "House - [4x04] - Guardian Angels.mp4")
+	if err := os.WriteFile(source, []byte("episode"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// Plan with a relative --dir, as the command line does by default
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := NewPlan[Match](os.DirFS("show"), "show", patterns[0], Options{
+		Template: DefaultTemplates[KindTV],
+		Policy:   ConflictAbort,
+	})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := plan.Write(buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadPlan(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if err := read.Apply(nil); err != nil {
+		t.Fatalf("apply from another directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "show", "House - s04e04 - Guardian Angels.mp4")); err != nil {
+		t.Errorf("expected the file to be renamed in place: %v", err)
+	}
+}
+
+func TestPlanPrintRelative(t *testing.T) {
+	dir := t.TempDir()
+	plan, err := NewPlan[Match](wrapNamesInFS([]string{"Show.S01E02.mkv"}), dir, patterns[1], Options{
+		Template: DefaultTemplates[KindTV],
+	})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	plan.Print(buf)
+	if expected := `Rename "Show.S01E02.mkv" -> "Show - s01e02.mkv"`; !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected paths relative to the plan's directory, got:\n%s", buf)
+	}
+}
@@ -2,85 +2,112 @@ package file
 
 import (
 	"fmt"
-	"html/template"
 	"io/fs"
 	"os"
-	"path/filepath"
-	"strings"
-
-	"github.com/elliotcubit/renamer/pkg/regexps"
+	"time"
 )
 
 type Match struct {
-	ShowName string `regexps:"name,required"`
-	Season   int    `regexps:"season,required"`
-	Episode  int    `regexps:"episode,required"`
-	Title    string `regexps:"title,required"`
+	ShowName string `regexps:"name,required" json:"name"`
+	// Season and episode numbers may also be written as roman numerals or
+	// in words, e.g. "Season Two" or "Part IV".
+	Season  int `regexps:"season,required,roman,words" json:"season"`
+	Episode int `regexps:"episode,required,roman,words" json:"episode"`
+	// EpisodeEnd is the last episode in a file holding several, or zero.
+	EpisodeEnd int    `regexps:"episode_end,roman,words" json:"episode_end,omitempty"`
+	Title      string `regexps:"title" json:"title"`
 }
 
-func RenameAllFiles(
-	fsys fs.FS,
-	dir string,
-	pattern *regexps.Regexp[Match],
-	dry bool,
-	outputTemplate string,
-) error {
-	if dry {
-		fmt.Printf("In %q, would:\n", dir)
+// Episodes formats the episode numbers of m the way Plex expects, e.g.
+// "e05" for a single episode or "e01-e02" for a multi-episode file.
+func (m Match) Episodes() string {
+	if m.EpisodeEnd > m.Episode {
+		return fmt.Sprintf("e%02d-e%02d", m.Episode, m.EpisodeEnd)
 	}
-	renamedFiles := 0
+	return fmt.Sprintf("e%02d", m.Episode)
+}
 
-	tmpl, err := template.New("output").Parse(outputTemplate)
-	if err != nil {
-		return fmt.Errorf("bad template: %w", err)
+// SeasonFolder returns the name of the folder Plex expects the season of m
+// to be in, e.g. "Season 02", or "Specials" for season 0.
+func (m Match) SeasonFolder() string {
+	if m.Season == 0 {
+		return "Specials"
 	}
+	return fmt.Sprintf("Season %02d", m.Season)
+}
 
-	buf := new(strings.Builder)
+// Daily is an episode of a show that is released daily, and is named by
+// its air date rather than its season and episode.
+type Daily struct {
+	ShowName string    `regexps:"name,required" json:"name"`
+	Date     time.Time `regexps:"date,required,layout=2006.01.02" json:"date"`
+	Title    string    `regexps:"title" json:"title"`
+}
 
-	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
-		defer buf.Reset()
+type Movie struct {
+	Title      string `regexps:"title,required" json:"title"`
+	Year       int    `regexps:"year,required" json:"year"`
+	Edition    string `regexps:"edition" json:"edition,omitempty"`
+	Resolution string `regexps:"resolution" json:"resolution,omitempty"`
+}
+
+// ApplyRenames performs renames in order, recording each in journal if it
+// is not nil. Targets on another filesystem are copied and then removed.
+func ApplyRenames(renames []Rename, journal *Journal) error {
+	for _, v := range renames {
+		err := applyRename(v.Source, v.Target, journal)
 		if err != nil {
 			return err
 		}
-
-		if d.IsDir() {
-			return nil
-		}
-
-		dir2, file := filepath.Split(path)
-		ext := filepath.Ext(file)
-		fullPath := filepath.Join(dir, path)
-
-		match := pattern.FindString(file)
-		if match != nil {
-			err := tmpl.Execute(buf, match)
+		for _, sidecar := range v.Sidecars {
+			err := applyRename(sidecar.Source, sidecar.Target, journal)
 			if err != nil {
-				return fmt.Errorf("apply template: %w", err)
-			}
-
-			newFile := buf.String()
-			newFile += ext
-
-			newPath := filepath.Join(dir, dir2, newFile)
-
-			if dry {
-				fmt.Printf("  Rename %q -> %q\n", path, newFile)
-				renamedFiles += 1
-			} else {
-				err := os.Rename(fullPath, newPath)
-				if err != nil {
-					return err
-				}
-				renamedFiles += 1
+				return err
 			}
 		}
+	}
+	return nil
+}
 
+func applyRename(source, target string, journal *Journal) error {
+	if source == target {
 		return nil
-	})
+	}
+	if err := move(source, target); err != nil {
+		return err
+	}
+	if journal != nil {
+		if err := journal.Record(source, target); err != nil {
+			return fmt.Errorf("journal: %w", err)
+		}
+	}
+	return nil
+}
+
+func RenameAllFiles[T Media](
+	fsys fs.FS,
+	dir string,
+	pattern Finder[T],
+	dry bool,
+	opts Options,
+	journal *Journal,
+) error {
+	plan, err := NewPlan(fsys, dir, pattern, opts)
+	if err != nil {
+		return err
+	}
 
 	if dry {
-		fmt.Printf("  Would rename %d files\n", renamedFiles)
+		plan.Print(os.Stdout)
+		return nil
 	}
 
-	return err
+	if err := ApplyRenames(plan.Renames, journal); err != nil {
+		return err
+	}
+	if len(plan.Skipped) > 0 {
+		fmt.Printf("In %q, did not rename:\n", dir)
+		plan.PrintSkipped(os.Stdout)
+	}
+	return nil
 }
@@ -0,0 +1,205 @@
+package file
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// SanitizeProfile describes the file names a filesystem, or the clients of a
+// network share, can handle. Rendered templates are cleaned up with a
+// profile before they are used as targets.
+type SanitizeProfile struct {
+	Name string
+	// Replacements maps characters that can't be used to what they are
+	// replaced with; characters mapped to "" are removed.
+	Replacements map[rune]string
+	// ControlChars removes control characters.
+	ControlChars bool
+	// ReservedNames are names that can't be used with any extension, such
+	// as "CON" on Windows. They are compared case-insensitively with the part
+	// of the name before the first dot, which gets an underscore appended.
+	ReservedNames []string
+	// TrimTrailing removes trailing dots and spaces.
+	TrimTrailing bool
+	// MaxBytes is the longest a file name may be, in bytes of UTF-8.
+	MaxBytes int
+	// Form is the Unicode normalization form names are converted to, if
+	// Normalize is set.
+	Form      norm.Form
+	Normalize bool
+}
+
+var windowsReplacements = map[rune]string{
+	':':  "-",
+	'"':  "'",
+	'\\': "-",
+	'|':  "-",
+	'<':  "",
+	'>':  "",
+	'?':  "",
+	'*':  "",
+}
+
+var windowsReservedNames = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
+
+var (
+	// ProfilePOSIX only enforces the length limit of most Linux filesystems.
+	ProfilePOSIX = &SanitizeProfile{
+		Name:     "posix",
+		MaxBytes: 255,
+	}
+	// ProfileWindows follows the rules of NTFS and the Windows API.
+	ProfileWindows = &SanitizeProfile{
+		Name:          "windows",
+		Replacements:  windowsReplacements,
+		ControlChars:  true,
+		ReservedNames: windowsReservedNames,
+		TrimTrailing:  true,
+		MaxBytes:      255,
+		Form:          norm.NFC,
+		Normalize:     true,
+	}
+	// ProfileSMB is for shares that are used by both Windows and other
+	// clients. Windows clients refuse the same names on a share as on NTFS,
+	// so it is the Windows profile under another name.
+	ProfileSMB = ProfileWindows
+	// ProfileMacOS follows the rules of APFS and HFS+, which store names
+	// decomposed.
+	ProfileMacOS = &SanitizeProfile{
+		Name:         "macos",
+		Replacements: map[rune]string{':': "-"},
+		ControlChars: true,
+		MaxBytes:     255,
+		Form:         norm.NFD,
+		Normalize:    true,
+	}
+)
+
+var SanitizeProfiles = []*SanitizeProfile{
+	ProfilePOSIX,
+	ProfileWindows,
+	ProfileMacOS,
+}
+
+// sanitizeAliases are other names profiles can be chosen by.
+var sanitizeAliases = map[string]*SanitizeProfile{
+	"smb": ProfileSMB,
+}
+
+func ParseSanitizeProfile(s string) (*SanitizeProfile, error) {
+	for _, v := range SanitizeProfiles {
+		if v.Name == s {
+			return v, nil
+		}
+	}
+	if v, ok := sanitizeAliases[s]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("unknown sanitize profile %q", s)
+}
+
+// Path sanitizes every element of a relative path rendered by a template,
+// which may use either "/" or the OS separator. The extension ext is added
+// to the last element, and is kept when it is shortened to fit MaxBytes,
+// leaving room for reserve more bytes.
+func (p *SanitizeProfile) Path(path, ext string, reserve int) string {
+	elems := strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == filepath.Separator
+	})
+	if len(elems) == 0 {
+		elems = []string{""}
+	}
+	for i, v := range elems {
+		if i == len(elems)-1 {
+			elems[i] = p.File(v, ext, reserve)
+		} else {
+			elems[i] = p.File(v, "", 0)
+		}
+	}
+	return filepath.Join(elems...)
+}
+
+// File sanitizes a single file name without its extension ext, and adds
+// the extension back.
+func (p *SanitizeProfile) File(name, ext string, reserve int) string {
+	name = p.Clean(name)
+	if name == "" {
+		name = "_"
+	}
+	stem, rest, dotted := strings.Cut(name, ".")
+	for _, v := range p.ReservedNames {
+		if strings.EqualFold(strings.TrimSpace(stem), v) {
+			name = stem + "_"
+			if dotted {
+				name += "." + rest
+			}
+			break
+		}
+	}
+
+	if p.Normalize {
+		ext = p.Form.String(ext)
+	}
+	if p.MaxBytes > 0 {
+		name = truncate(name, p.MaxBytes-len(ext)-reserve)
+		if p.TrimTrailing {
+			name = strings.TrimRight(name, ". ")
+		}
+	}
+	return name + ext
+}
+
+// Clean replaces or removes the characters of s that the profile doesn't
+// allow, and normalizes it. It doesn't enforce reserved names or lengths.
+func (p *SanitizeProfile) Clean(s string) string {
+	if p.Replacements[':'] != "" {
+		s = strings.ReplaceAll(s, ": ", " "+p.Replacements[':']+" ")
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if repl, ok := p.Replacements[r]; ok {
+			b.WriteString(repl)
+		} else if r == '/' || r == 0 || (p.ControlChars && r < 0x20) {
+			continue
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	s = b.String()
+
+	if p.TrimTrailing {
+		s = strings.TrimRight(s, ". ")
+	}
+	if p.Normalize {
+		s = p.Form.String(s)
+	}
+	return s
+}
+
+// truncate shortens s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if n < 1 {
+		n = 1
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// sanitize makes s safe to use as a file name on common filesystems, by
+// cleaning it with the rules of Windows, which are the strictest.
+func sanitize(s string) string {
+	return strings.Join(strings.Fields(ProfileWindows.Clean(s)), " ")
+}
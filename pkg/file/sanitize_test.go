@@ -0,0 +1,78 @@
+package file
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeProfiles(t *testing.T) {
+	tests := []struct {
+		profile  *SanitizeProfile
+		path     string
+		expected string
+	}{
+		{ProfilePOSIX, "Show/Season 01/Show: Part 1 <x>?", "Show/Season 01/Show: Part 1 <x>?.mkv"},
+		{ProfileWindows, "Show/Season 01/Show: Part 1 <x>?", "Show/Season 01/Show - Part 1 x.mkv"},
+		{ProfileWindows, "Mr. Robot./CON", "Mr. Robot/CON_.mkv"},
+		{ProfileWindows, "aux.stuff", "aux_.stuff.mkv"},
+		{ProfileWindows, "Say \"Hi\" | Bye", "Say 'Hi' - Bye.mkv"},
+		{ProfileSMB, "A:B\\C", "A-B-C.mkv"},
+		{ProfileMacOS, "Cafe\u0301: Time?", "Cafe\u0301 - Time?.mkv"},
+		{ProfileMacOS, "Caf\u00e9", "Cafe\u0301.mkv"},
+		{ProfileWindows, "Cafe\u0301", "Caf\u00e9.mkv"},
+	}
+
+	for _, v := range tests {
+		got := v.profile.Path(v.path, ".mkv", 0)
+		if got != filepath.FromSlash(v.expected) {
+			t.Errorf("%s: sanitize %q: expected %q, got %q", v.profile.Name, v.path, v.expected, got)
+		}
+	}
+}
+
+func TestSanitizeLength(t *testing.T) {
+	long := strings.Repeat("é", 200)
+	got := ProfilePOSIX.File(long, ".mkv", len(".en.forced.srt")-len(".mkv"))
+	if len(got)+len(".en.forced.srt")-len(".mkv") > 255 {
+		t.Errorf("expected at most 255 bytes with the longest sidecar, got %d", len(got))
+	}
+	if !strings.HasSuffix(got, "é.mkv") {
+		t.Errorf("expected the extension to be kept after a whole character, got %q", got)
+	}
+}
+
+func TestPlanSanitize(t *testing.T) {
+	fs := wrapNamesInFS([]string{
+		"Show.S01E02.What.If.mkv",
+	})
+
+	renames, err := PlanRenames[Match](fs, ".", patterns[1], Options{
+		Template: `{{ .ShowName }}: {{ .Title | undot }}?`,
+		Sanitize: ProfileWindows,
+	})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(renames) != 1 {
+		t.Fatalf("expected 1 rename, got %d", len(renames))
+	}
+	if filepath.Base(renames[0].Target) != "Show - What If.mkv" {
+		t.Errorf("expected sanitized target, got %q", renames[0].Target)
+	}
+	if renames[0].Rendered != "Show: What If?" {
+		t.Errorf("expected the rendered name to be kept, got %q", renames[0].Rendered)
+	}
+}
+
+func TestParseSanitizeProfile(t *testing.T) {
+	for name, expected := range map[string]*SanitizeProfile{"windows": ProfileWindows, "smb": ProfileWindows, "macos": ProfileMacOS} {
+		got, err := ParseSanitizeProfile(name)
+		if err != nil || got != expected {
+			t.Errorf("%q: expected the %s profile, got %v, %v", name, expected.Name, got, err)
+		}
+	}
+	if _, err := ParseSanitizeProfile("fat"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
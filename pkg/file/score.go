@@ -0,0 +1,227 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ambiguityMargin is how close the scores of two patterns must be for the
+// choice between them to be ambiguous.
+const ambiguityMargin = 0.5
+
+// maxExamples is how many example matches are kept for each candidate.
+const maxExamples = 3
+
+// Candidate is a pattern scored against the files of a directory.
+type Candidate[T any] struct {
+	Pattern *Pattern[T]
+	// Matched is how many of the Total files the pattern matched. Files
+	// that are okay not to match count as matched.
+	Matched int
+	Total   int
+	// Populated is the average number of fields filled in per match.
+	Populated float64
+	// Consistency is the average share of matches that agree on the most
+	// common show name and season, or 1 for kinds without them.
+	Consistency float64
+	// Score ranks candidates that match every file: the higher, the more
+	// the pattern got out of the file names.
+	Score    float64
+	Examples []Example[T]
+	// matches holds what the pattern matched in each file.
+	matches map[string]*T
+}
+
+// Example is what a pattern matched in a file name.
+type Example[T any] struct {
+	File  string
+	Match *T
+}
+
+// Coverage is the share of files that c matched.
+func (c *Candidate[T]) Coverage() float64 {
+	if c.Total == 0 {
+		return 1
+	}
+	return float64(c.Matched) / float64(c.Total)
+}
+
+// AmbiguousPatternError is returned by inference when several patterns of
+// the same priority match every file, and their scores are too close to
+// choose between them.
+type AmbiguousPatternError struct {
+	Candidates []AmbiguousCandidate
+}
+
+// AmbiguousCandidate describes a pattern in an AmbiguousPatternError.
+type AmbiguousCandidate struct {
+	Name  string
+	Score float64
+	// Examples show what the pattern extracts from some of the files, as
+	// "file -> fields".
+	Examples []string
+}
+
+func (e *AmbiguousPatternError) Error() string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "%d patterns match equally well:", len(e.Candidates))
+	for _, v := range e.Candidates {
+		fmt.Fprintf(b, "\n  %s (score %.2f)", v.Name, v.Score)
+		for _, example := range v.Examples {
+			fmt.Fprintf(b, "\n    %s", example)
+		}
+	}
+	return b.String()
+}
+
+func newAmbiguousPatternError[T any](candidates []*Candidate[T]) *AmbiguousPatternError {
+	err := new(AmbiguousPatternError)
+	for _, v := range candidates {
+		candidate := AmbiguousCandidate{Name: v.Pattern.Name, Score: v.Score}
+		for _, example := range v.Examples {
+			fields, _ := json.Marshal(example.Match)
+			candidate.Examples = append(candidate.Examples, fmt.Sprintf("%q -> %s", example.File, fields))
+		}
+		err.Candidates = append(err.Candidates, candidate)
+	}
+	return err
+}
+
+// RankPatterns scores every pattern against the files in fsys, which is the
+// directory dir. Candidates that match every file come first, then those of
+// a higher priority, then those with a higher score.
+func RankPatterns[T any](fsys fs.FS, dir string, patterns []*Pattern[T]) ([]*Candidate[T], error) {
+	return rankPatterns(fsys, dir, patterns, false)
+}
+
+// RankPathPatterns is RankPatterns for patterns that match the paths of
+// files relative to dir, with "/" between their parts, instead of their
+// names.
+func RankPathPatterns[T any](fsys fs.FS, dir string, patterns []*Pattern[T]) ([]*Candidate[T], error) {
+	return rankPatterns(fsys, dir, patterns, true)
+}
+
+func rankPatterns[T any](fsys fs.FS, dir string, patterns []*Pattern[T], matchPath bool) ([]*Candidate[T], error) {
+	paths, err := listFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sidecars are renamed along with their video, so they needn't match
+	isSidecar := make(map[string]bool)
+	for _, v := range findSidecars(paths) {
+		for _, sidecar := range v {
+			isSidecar[sidecar] = true
+		}
+	}
+	var files []string
+	for _, path := range paths {
+		if !isSidecar[path] {
+			files = append(files, path)
+		}
+	}
+
+	candidates := make([]*Candidate[T], len(patterns))
+	for i, v := range patterns {
+		candidates[i] = scorePattern(v, dir, files, matchPath)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Coverage() != b.Coverage() {
+			return a.Coverage() > b.Coverage()
+		}
+		if a.Pattern.Priority != b.Pattern.Priority {
+			return a.Pattern.Priority > b.Pattern.Priority
+		}
+		return a.Score > b.Score
+	})
+	return candidates, nil
+}
+
+func scorePattern[T any](pattern *Pattern[T], dir string, paths []string, matchPath bool) *Candidate[T] {
+	c := &Candidate[T]{Pattern: pattern, Total: len(paths), matches: make(map[string]*T)}
+
+	var matches []*T
+	for _, path := range paths {
+		file := filepath.Base(path)
+		if isOkay(file) {
+			c.Matched++
+			continue
+		}
+		subject := file
+		if matchPath {
+			subject = path
+		}
+		match, err := pattern.ParseStringContext(subject, PathContext(filepath.Join(dir, path)))
+		if err != nil {
+			continue
+		}
+		c.Matched++
+		c.matches[path] = match
+		matches = append(matches, match)
+		if len(c.Examples) < maxExamples {
+			c.Examples = append(c.Examples, Example[T]{File: subject, Match: match})
+		}
+	}
+
+	c.Consistency = 1
+	if len(matches) > 0 {
+		populated := 0
+		for _, v := range matches {
+			populated += countPopulated(reflect.ValueOf(v).Elem())
+		}
+		c.Populated = float64(populated) / float64(len(matches))
+		c.Consistency = consistency(matches, "ShowName", "Season")
+	}
+	c.Score = c.Populated + 2*c.Consistency
+	return c
+}
+
+// sameMatches reports whether c and other extract the same fields from
+// every file.
+func (c *Candidate[T]) sameMatches(other *Candidate[T]) bool {
+	return reflect.DeepEqual(c.matches, other.matches)
+}
+
+// countPopulated counts the fields of the struct v that aren't zero.
+func countPopulated(v reflect.Value) int {
+	n := 0
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Field(i).IsZero() {
+			n++
+		}
+	}
+	return n
+}
+
+// consistency returns the average, over the named fields that T has, of the
+// share of matches that have the most common value of the field.
+func consistency[T any](matches []*T, fields ...string) float64 {
+	total, n := 0.0, 0
+	for _, name := range fields {
+		if _, ok := reflect.TypeOf(*new(T)).FieldByName(name); !ok {
+			continue
+		}
+		counts := make(map[any]int)
+		most := 0
+		for _, v := range matches {
+			value := reflect.ValueOf(v).Elem().FieldByName(name).Interface()
+			counts[value]++
+			if counts[value] > most {
+				most = counts[value]
+			}
+		}
+		total += float64(most) / float64(len(matches))
+		n++
+	}
+	if n == 0 {
+		return 1
+	}
+	return total / float64(n)
+}
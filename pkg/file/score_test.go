@@ -0,0 +1,113 @@
+package file
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/elliotcubit/renamer/pkg/regexps"
+)
+
+func namedPattern(name string, priority int, pattern string) *Pattern[Match] {
+	return &Pattern[Match]{
+		Regexp:   regexps.MustCompile[Match](pattern),
+		Name:     name,
+		Priority: priority,
+	}
+}
+
+func TestRankPatterns(t *testing.T) {
+	fs := wrapNamesInFS([]string{
+		"Show.S01E02.Title.mkv",
+		"Show.S01E03.Other.mkv",
+	})
+
+	// Both match every file, but only one gets the titles
+	titled := namedPattern("titled", 0, `^(?P<name>[^.]+)\.S(?P<season>\d+)E(?P<episode>\d+)\.(?P<title>[^.]+)\.`)
+	untitled := namedPattern("untitled", 0, `^(?P<name>[^.]+)\.S(?P<season>\d+)E(?P<episode>\d+)`)
+	other := namedPattern("other", 10, `^(?P<name>.+) (?P<season>\d+)x(?P<episode>\d+)`)
+
+	candidates, err := RankPatterns(fs, ".", []*Pattern[Match]{other, untitled, titled})
+	if err != nil {
+		t.Fatalf("rank: %v", err)
+	}
+	names := []string{candidates[0].Pattern.Name, candidates[1].Pattern.Name, candidates[2].Pattern.Name}
+	if names[0] != "titled" || names[1] != "untitled" || names[2] != "other" {
+		t.Errorf("unexpected order %v", names)
+	}
+	if candidates[0].Populated != 4 || candidates[1].Populated != 3 {
+		t.Errorf("expected 4 and 3 populated fields, got %v and %v", candidates[0].Populated, candidates[1].Populated)
+	}
+
+	pattern, err := inferPattern(fs, ".", []*Pattern[Match]{untitled, titled})
+	if err != nil || pattern != titled {
+		t.Errorf("expected the titled pattern to be inferred, got %v, %v", pattern, err)
+	}
+}
+
+func TestInferAmbiguous(t *testing.T) {
+	fs := wrapNamesInFS([]string{
+		"Show - 1x02.mkv",
+	})
+
+	// Both match every file as well as each other, but disagree
+	a := namedPattern("a", 0, `^(?P<name>.+) - (?P<season>\d+)x(?P<episode>\d+)`)
+	b := namedPattern("b", 0, `^(?P<name>.+) - (?P<episode>\d+)x(?P<season>\d+)`)
+
+	_, err := inferPattern(fs, ".", []*Pattern[Match]{a, b})
+	var ambiguous *AmbiguousPatternError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expected an ambiguous pattern error, got %v", err)
+	}
+	if len(ambiguous.Candidates) != 2 || len(ambiguous.Candidates[0].Examples) != 1 {
+		t.Errorf("expected both candidates with examples, got %+v", ambiguous.Candidates)
+	}
+
+	// A higher priority settles it
+	b.Priority = 1
+	pattern, err := inferPattern(fs, ".", []*Pattern[Match]{a, b})
+	if err != nil || pattern != b {
+		t.Errorf("expected the higher priority pattern, got %v, %v", pattern, err)
+	}
+
+	// As do the same results
+	pattern, err = inferPattern(fs, ".", []*Pattern[Match]{a, namedPattern("c", 0, `^(?P<name>.+?) - (?P<season>\d+)x(?P<episode>\d+)`)})
+	if err != nil || pattern != a {
+		t.Errorf("expected the first of equivalent patterns, got %v, %v", pattern, err)
+	}
+
+	// With more files, a keeps the same season while b doesn't
+	fs = wrapNamesInFS([]string{
+		"Show - 1x02.mkv",
+		"Show - 1x03.mkv",
+	})
+	b.Priority = 0
+	pattern, err = inferPattern(fs, ".", []*Pattern[Match]{b, a})
+	if err != nil || pattern != a {
+		t.Errorf("expected the more consistent pattern, got %v, %v", pattern, err)
+	}
+}
+
+func TestRankPathPatterns(t *testing.T) {
+	fs := wrapNamesInFS([]string{
+		"Show.S02/01.mkv",
+		"Show.S02/02.mkv",
+	})
+
+	path := namedPattern("path", 0, `^(?P<name>[^/]+)\.S(?P<season>\d+)/(?P<episode>\d+)\.mkv$`)
+
+	candidates, err := RankPathPatterns(fs, ".", []*Pattern[Match]{path})
+	if err != nil {
+		t.Fatalf("rank: %v", err)
+	}
+	if candidates[0].Coverage() != 1 || candidates[0].Examples[0].File != "Show.S02/01.mkv" {
+		t.Errorf("expected the path pattern to match every path, got %+v", candidates[0])
+	}
+
+	candidates, err = RankPatterns(fs, ".", []*Pattern[Match]{path})
+	if err != nil {
+		t.Fatalf("rank: %v", err)
+	}
+	if candidates[0].Matched != 0 {
+		t.Errorf("expected the path pattern not to match file names, got %d", candidates[0].Matched)
+	}
+}
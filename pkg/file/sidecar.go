@@ -0,0 +1,178 @@
+package file
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// Sidecar is a file that belongs to a video, such as subtitles or artwork,
+// and is renamed along with it.
+type Sidecar struct {
+	Source  string    `json:"source"`
+	Target  string    `json:"target"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	// suffix is what follows the video's name in the target, e.g.
+	// ".en.forced.srt" or "-thumb.jpg".
+	suffix string
+}
+
+var videoExts = map[string]bool{
+	".avi":  true,
+	".flv":  true,
+	".m2ts": true,
+	".m4v":  true,
+	".mkv":  true,
+	".mov":  true,
+	".mp4":  true,
+	".mpeg": true,
+	".mpg":  true,
+	".ts":   true,
+	".webm": true,
+	".wmv":  true,
+}
+
+var subtitleExts = map[string]bool{
+	".ass": true,
+	".idx": true,
+	".smi": true,
+	".srt": true,
+	".ssa": true,
+	".sub": true,
+	".sup": true,
+	".vtt": true,
+}
+
+var sidecarExts = map[string]bool{
+	".jpeg": true,
+	".jpg":  true,
+	".nfo":  true,
+	".png":  true,
+	".tbn":  true,
+}
+
+// subtitleFlags are the subtitle suffixes Plex understands, besides the
+// language, keyed by the spellings releases use for them.
+var subtitleFlags = map[string]string{
+	"forced": "forced",
+	"sdh":    "sdh",
+	"hi":     "sdh",
+	"cc":     "cc",
+}
+
+// languageCodes maps the language names and three-letter codes commonly
+// found in subtitle file names to two-letter codes.
+var languageCodes = map[string]string{
+	"english":    "en",
+	"eng":        "en",
+	"spanish":    "es",
+	"spa":        "es",
+	"french":     "fr",
+	"fre":        "fr",
+	"fra":        "fr",
+	"german":     "de",
+	"ger":        "de",
+	"deu":        "de",
+	"italian":    "it",
+	"ita":        "it",
+	"portuguese": "pt",
+	"por":        "pt",
+	"dutch":      "nl",
+	"dut":        "nl",
+	"nld":        "nl",
+	"japanese":   "ja",
+	"jpn":        "ja",
+	"chinese":    "zh",
+	"chi":        "zh",
+	"zho":        "zh",
+	"korean":     "ko",
+	"kor":        "ko",
+	"russian":    "ru",
+	"rus":        "ru",
+}
+
+func isSidecarExt(ext string) bool {
+	ext = strings.ToLower(ext)
+	return subtitleExts[ext] || sidecarExts[ext]
+}
+
+// findSidecars groups the files in paths that belong to a video in the same
+// directory, keyed by the path of the video. A file belongs to a video if
+// its name starts with the name of the video without its extension,
+// followed by a "." or "-", and it has the extension of a sidecar.
+func findSidecars(paths []string) map[string][]string {
+	stems := make(map[string]map[string]string)
+	for _, v := range paths {
+		dir, file := path.Split(v)
+		ext := path.Ext(file)
+		if !videoExts[strings.ToLower(ext)] {
+			continue
+		}
+		if stems[dir] == nil {
+			stems[dir] = make(map[string]string)
+		}
+		stems[dir][strings.TrimSuffix(file, ext)] = v
+	}
+
+	sidecars := make(map[string][]string)
+	for _, v := range paths {
+		dir, file := path.Split(v)
+		if !isSidecarExt(path.Ext(file)) {
+			continue
+		}
+		best := ""
+		for stem := range stems[dir] {
+			if len(file) > len(stem) && strings.HasPrefix(file, stem) && strings.ContainsRune(".-", rune(file[len(stem)])) && len(stem) > len(best) {
+				best = stem
+			}
+		}
+		if best != "" {
+			video := stems[dir][best]
+			sidecars[video] = append(sidecars[video], v)
+		}
+	}
+	return sidecars
+}
+
+// sidecarSuffix converts what follows the video's name in a sidecar's name
+// to what should follow it in the target. The language and flags of
+// subtitles are kept in Plex's "Name.en.forced.srt" convention; everything
+// else is kept as is.
+func sidecarSuffix(suffix string) string {
+	ext := path.Ext(suffix)
+	if !subtitleExts[strings.ToLower(ext)] {
+		return suffix
+	}
+
+	var language string
+	var flags []string
+	for _, v := range strings.FieldsFunc(strings.TrimSuffix(suffix, ext), func(r rune) bool { return r == '.' || r == '-' || r == '_' }) {
+		v = strings.ToLower(v)
+		if flag, ok := subtitleFlags[v]; ok {
+			flags = append(flags, flag)
+		} else if code, ok := languageCodes[v]; ok && language == "" {
+			language = code
+		} else if len(v) == 2 && isLetters(v) && language == "" {
+			language = v
+		}
+	}
+
+	parts := flags
+	if language != "" {
+		parts = append([]string{language}, flags...)
+	}
+	if len(parts) == 0 {
+		return ext
+	}
+	return "." + strings.Join(parts, ".") + ext
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package file
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSidecarSuffix(t *testing.T) {
+	tests := map[string]string{
+		".en.srt":          ".en.srt",
+		".eng.forced.srt":  ".en.forced.srt",
+		".English.SDH.srt": ".en.sdh.srt",
+		".forced.srt":      ".forced.srt",
+		".srt":             ".srt",
+		".nfo":             ".nfo",
+		"-thumb.jpg":       "-thumb.jpg",
+	}
+
+	for suffix, expected := range tests {
+		if got := sidecarSuffix(suffix); got != expected {
+			t.Errorf("sidecar suffix of %q: expected %q, got %q", suffix, expected, got)
+		}
+	}
+}
+
+func TestPlanSidecars(t *testing.T) {
+	fs := wrapNamesInFS([]string{
+		"Show.S01E02.Title.720p.mkv",
+		"Show.S01E02.Title.720p.en.forced.srt",
+		"Show.S01E02.Title.720p-thumb.jpg",
+		"Show.S01E02.Title.720p.nfo",
+		"Show.S01E03.Other.720p.mkv",
+		"Show.S01E03.Other.720p.TRAILER.mkv",
+	})
+
+	renames, err := PlanRenames[Match](fs, ".", patterns[1], Options{Template: DefaultTemplates[KindTV]})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+
+	sidecars := make(map[string]string)
+	for _, v := range renames {
+		for _, sidecar := range v.Sidecars {
+			sidecars[filepath.Base(sidecar.Source)] = filepath.Base(sidecar.Target)
+		}
+	}
+
+	expected := map[string]string{
+		"Show.S01E02.Title.720p.en.forced.srt": "Show - s01e02 - Title.en.forced.srt",
+		"Show.S01E02.Title.720p-thumb.jpg":     "Show - s01e02 - Title-thumb.jpg",
+		"Show.S01E02.Title.720p.nfo":           "Show - s01e02 - Title.nfo",
+	}
+	if len(sidecars) != len(expected) {
+		t.Errorf("expected %d sidecars, got: %v", len(expected), sidecars)
+	}
+	for source, target := range expected {
+		if sidecars[source] != filepath.FromSlash(target) {
+			t.Errorf("sidecar %q: expected %q, got %q", source, target, sidecars[source])
+		}
+	}
+}
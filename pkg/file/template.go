@@ -0,0 +1,68 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/elliotcubit/renamer/pkg/regexps"
+)
+
+// templateFuncs are the functions available in output templates, besides
+// the text functions they share with the defaults of patterns. Functions
+// taking options take them first, so that they can be used in pipelines,
+// e.g. {{ .Season | pad 2 }}.
+var templateFuncs = template.FuncMap{
+	"pad":      pad,
+	"title":    titleCase,
+	"undot":    undot,
+	"sanitize": sanitize,
+	"group":    noGroup,
+}
+
+// parseTemplate parses an output template with regexps.TextFuncs and
+// templateFuncs.
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("output").Funcs(regexps.TextFuncs()).Funcs(templateFuncs).Parse(text)
+}
+
+// noGroup stands in for the group function, which returns the value of a
+// group captured from the file being renamed, e.g. {{ group "quality" }}.
+// It is replaced for each file, so that templates can use groups that aren't
+// fields of the match, such as parts of its path.
+func noGroup(name string) string {
+	return ""
+}
+
+// pad formats v with leading zeroes to at least width digits.
+func pad(width int, v any) string {
+	s := fmt.Sprint(v)
+	if len(s) >= width {
+		return s
+	}
+	return strings.Repeat("0", width-len(s)) + s
+}
+
+// titleCase upper-cases the first letter of every word in s, and
+// lower-cases the rest.
+func titleCase(s string) string {
+	words := strings.Split(s, " ")
+	for i, v := range words {
+		r, size := utf8.DecodeRuneInString(v)
+		if size == 0 {
+			continue
+		}
+		words[i] = string(unicode.ToUpper(r)) + strings.ToLower(v[size:])
+	}
+	return strings.Join(words, " ")
+}
+
+// undot replaces the dots and underscores that releases use instead of
+// spaces with spaces.
+func undot(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return r == '.' || r == '_' || r == ' '
+	}), " ")
+}
@@ -0,0 +1,42 @@
+package file
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	type testcase struct {
+		template string
+		expected string
+	}
+
+	match := &Match{ShowName: "tom.and_jerry", Season: 2, Episode: 5, Title: "Tom & Jerry's: Day Out?"}
+
+	tests := []testcase{
+		{"{{ .Title }}", "Tom & Jerry's: Day Out?"},
+		{"s{{ pad 2 .Season }}e{{ .Episode | pad 3 }}", "s02e005"},
+		{"{{ .ShowName | undot | title }}", "Tom And Jerry"},
+		{"{{ .ShowName | upper }} {{ .Title | lower }}", "TOM.AND_JERRY tom & jerry's: day out?"},
+		{"{{ trim \"  x  \" }}", "x"},
+		{"{{ .Title | replace \"'s\\\\b\" \"s\" }}", "Tom & Jerrys: Day Out?"},
+		{"{{ .Title | sanitize }}", "Tom & Jerry's - Day Out"},
+		{"{{ .ShowName }} - s{{ pad 2 .Season }}{{ .Episodes }}", "tom.and_jerry - s02e05"},
+	}
+
+	for _, test := range tests {
+		tmpl, err := parseTemplate(test.template)
+		if err != nil {
+			t.Errorf("parse %q: %v", test.template, err)
+			continue
+		}
+		buf := new(strings.Builder)
+		if err := tmpl.Execute(buf, match); err != nil {
+			t.Errorf("execute %q: %v", test.template, err)
+			continue
+		}
+		if buf.String() != test.expected {
+			t.Errorf("execute %q: expected %q, got %q", test.template, test.expected, buf.String())
+		}
+	}
+}
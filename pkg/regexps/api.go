@@ -18,11 +18,16 @@ package regexps
 
 import (
 	"regexp"
+	"regexp/syntax"
+	"text/template"
+	"unicode/utf8"
 )
 
 type Regexp[T any] struct {
 	matcher  *regexp.Regexp
 	defaults map[string]string
+	// templates are the defaults that are evaluated for each match
+	templates map[string]*template.Template
 }
 
 func MatchString[T any](expr, target string) (bool, error) {
@@ -52,9 +57,15 @@ func CompileWithDefaults[T any](expr string, defaults map[string]string) (*Regex
 		return nil, err
 	}
 
+	templates, err := parseDefaults(defaults)
+	if err != nil {
+		return nil, err
+	}
+
 	retv := &Regexp[T]{
-		matcher:  matcher,
-		defaults: defaults,
+		matcher:   matcher,
+		defaults:  defaults,
+		templates: templates,
 	}
 
 	// Return the validated pattern
@@ -94,34 +105,240 @@ func (r *Regexp[T]) FindAll(b []byte) []*T {
 	return r.FindAllString(string(b))
 }
 
+// FindString returns the struct filled from the leftmost match of s, or nil
+// if there is no match or it can't be converted. ParseString tells those
+// apart.
 func (r *Regexp[T]) FindString(s string) *T {
-	match := r.matcher.FindStringSubmatch(s)
-	if match == nil {
-		return nil
+	retv, _ := r.ParseString(s)
+	return retv
+}
+
+// Parse is ParseString for a byte slice.
+func (r *Regexp[T]) Parse(b []byte) (*T, error) {
+	return r.ParseString(string(b))
+}
+
+// ParseString returns the struct filled from the leftmost match of s. If
+// there is no match, the error is a *NoMatchFoundError; if a required group
+// is empty, a *RequiredGroupIsEmpty; and if a group can't be converted to
+// the type of its field, a *ParseError.
+func (r *Regexp[T]) ParseString(s string) (*T, error) {
+	return r.ParseStringContext(s, nil)
+}
+
+// ParseStringContext is ParseString with values that defaults which are
+// templates can use besides the groups of the match, such as the directory
+// a file name is in.
+func (r *Regexp[T]) ParseStringContext(s string, context map[string]string) (*T, error) {
+	loc := r.matcher.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return nil, &NoMatchFoundError{}
 	}
-	targetRef, _ := validateTarget(new(T))
-	err := r.fillTarget(r.matchGroupMap(match), targetRef)
+	return r.fill(s, loc, context)
+}
+
+// ParseStringGroups is ParseStringContext that also returns the values of
+// the named groups of the match, as Groups does, without matching s again.
+// The groups are returned even if the match can't be converted.
+func (r *Regexp[T]) ParseStringGroups(s string, context map[string]string) (*T, map[string]string, error) {
+	loc := r.matcher.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return nil, nil, &NoMatchFoundError{}
+	}
+	match, err := r.fill(s, loc, context)
+	return match, r.groups(s, loc), err
+}
+
+// fill returns the struct filled from the match of s at loc
+func (r *Regexp[T]) fill(s string, loc []int, context map[string]string) (*T, error) {
+	match, err := r.newMatchValues(s, loc, context)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	targetRef, _ := validateTarget(new(T))
+	if err := r.fillTarget(match, targetRef); err != nil {
+		return nil, err
 	}
 	retv := targetRef.Interface().(T)
-	return &retv
+	return &retv, nil
 }
 
+// FindAllString returns the structs filled from every successive match of s
+// that can be converted, or nil if there are none. ParseAllString also
+// returns the reason each of the others couldn't be.
 func (r *Regexp[T]) FindAllString(s string) []*T {
-	matches := r.matcher.FindAllStringSubmatch(s, -1)
-	if matches == nil {
-		return nil
+	var retv []*T
+	r.EachString(s, func(m Match[T]) bool {
+		if m.Err == nil {
+			retv = append(retv, m.Value)
+		}
+		return true
+	})
+	return retv
+}
+
+// Match is a single match of a pattern in its input.
+type Match[T any] struct {
+	// Value is the struct filled from the match, or nil if Err is set.
+	Value *T
+	// Start and End are the byte offsets of the match in the input.
+	Start, End int
+	// Err is why the match couldn't be converted, as returned by ParseString.
+	Err error
+}
+
+// FindAllIndex is FindAllStringIndex for a byte slice.
+func (r *Regexp[T]) FindAllIndex(b []byte) []Match[T] {
+	return r.FindAllStringIndex(string(b))
+}
+
+// FindAllStringIndex is FindAllString with the position of each match in s.
+func (r *Regexp[T]) FindAllStringIndex(s string) []Match[T] {
+	var retv []Match[T]
+	r.EachString(s, func(m Match[T]) bool {
+		if m.Err == nil {
+			retv = append(retv, m)
+		}
+		return true
+	})
+	return retv
+}
+
+// ParseAll is ParseAllString for a byte slice.
+func (r *Regexp[T]) ParseAll(b []byte) []Match[T] {
+	return r.ParseAllString(string(b))
+}
+
+// ParseAllString returns every successive match of s, including those that
+// couldn't be converted, which have Err set instead of Value.
+func (r *Regexp[T]) ParseAllString(s string) []Match[T] {
+	var retv []Match[T]
+	r.EachString(s, func(m Match[T]) bool {
+		retv = append(retv, m)
+		return true
+	})
+	return retv
+}
+
+// Each is EachString for a byte slice.
+func (r *Regexp[T]) Each(b []byte, fn func(Match[T]) bool) {
+	r.EachString(string(b), fn)
+}
+
+// EachString calls fn with each successive match of s in turn, as
+// ParseAllString would return them, until fn returns false. Matches are
+// found one at a time, and each struct is only filled when fn is about to be
+// called with it, so fn can stop early without the rest of a large input
+// being scanned. Patterns with assertions that look behind where a match
+// starts, such as ^ or \b, are matched against the whole input first.
+func (r *Regexp[T]) EachString(s string, fn func(Match[T]) bool) {
+	if needsContext(r.matcher) {
+		for _, loc := range r.matcher.FindAllStringSubmatchIndex(s, -1) {
+			m := Match[T]{Start: loc[0], End: loc[1]}
+			m.Value, m.Err = r.fill(s, loc, nil)
+			if !fn(m) {
+				return
+			}
+		}
+		return
 	}
-	retv := make([]*T, len(matches))
-	for _, match := range matches {
-		targetRef, _ := validateTarget(new(T))
-		err := r.fillTarget(r.matchGroupMap(match), targetRef)
-		if err != nil {
-			return nil
+
+	prevEnd := -1
+	for pos := 0; pos <= len(s); {
+		loc := r.matcher.FindStringSubmatchIndex(s[pos:])
+		if loc == nil {
+			return
+		}
+		for i := range loc {
+			if loc[i] >= 0 {
+				loc[i] += pos
+			}
+		}
+
+		// Like FindAllString, skip an empty match right after the previous
+		// match, and step past empty matches so the next search moves on.
+		if loc[1] == loc[0] {
+			_, width := utf8.DecodeRuneInString(s[loc[1]:])
+			pos = loc[1] + width
+			if width == 0 {
+				pos++
+			}
+			if loc[1] == prevEnd {
+				continue
+			}
+		} else {
+			pos = loc[1]
+		}
+		prevEnd = loc[1]
+
+		m := Match[T]{Start: loc[0], End: loc[1]}
+		m.Value, m.Err = r.fill(s, loc, nil)
+		if !fn(m) {
+			return
 		}
-		nxt := targetRef.Interface().(T)
-		retv = append(retv, &nxt)
 	}
-	return retv
+}
+
+// needsContext reports whether re has assertions that depend on the text
+// before the position matching starts at, so it can't be matched against
+// the rest of a string on its own.
+func needsContext(re *regexp.Regexp) bool {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return true
+	}
+	var walk func(*syntax.Regexp) bool
+	walk = func(re *syntax.Regexp) bool {
+		switch re.Op {
+		case syntax.OpBeginLine, syntax.OpBeginText, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+			return true
+		}
+		for _, v := range re.Sub {
+			if walk(v) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(parsed)
+}
+
+// Groups returns the values of the named groups in the leftmost match of s,
+// or nil if there is no match.
+func (r *Regexp[T]) Groups(s string) map[string]string {
+	loc := r.matcher.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return nil
+	}
+	return r.groups(s, loc)
+}
+
+// groups returns the values of the named groups in the match of s at loc.
+func (r *Regexp[T]) groups(s string, loc []int) map[string]string {
+	ret := make(map[string]string)
+	for name, values := range r.matchGroupMap(s, loc) {
+		ret[name] = firstValue(values)
+	}
+	return ret
+}
+
+// Participated reports, for each named group, whether it took part in the
+// leftmost match of s, or returns nil if there is no match. A group that took
+// part may still have matched the empty string; one that didn't, such as in an
+// optional part of the pattern that was skipped, is given its default.
+func (r *Regexp[T]) Participated(s string) map[string]bool {
+	loc := r.matcher.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return nil
+	}
+	ret := make(map[string]bool)
+	for name, values := range r.matchGroupMap(s, loc) {
+		ret[name] = participated(values)
+	}
+	return ret
+}
+
+// String returns the source text used to compile the regular expression.
+func (r *Regexp[T]) String() string {
+	return r.matcher.String()
 }
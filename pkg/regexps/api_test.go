@@ -16,7 +16,17 @@
 
 package regexps
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
 
 func TestMatching(t *testing.T) {
 	type s struct {
@@ -116,3 +126,458 @@ func TestCompile(t *testing.T) {
 		}
 	}
 }
+
+func TestOptional(t *testing.T) {
+	type s struct {
+		Foo int `regexps:"foo,required"`
+		Bar int `regexps:"bar"`
+		Baz int `regexps:"baz"`
+	}
+
+	pattern := MustCompileWithDefaults[s](`(?P<foo>\d+)(?:-(?P<bar>\d+))?(?:_(?P<baz>\d+))?`, map[string]string{"baz": "3"})
+
+	match := pattern.FindString("1")
+	if match == nil {
+		t.Fatal("no match")
+	}
+	if match.Foo != 1 || match.Bar != 0 || match.Baz != 3 {
+		t.Errorf("wrong values, got: %+v", match)
+	}
+
+	match = pattern.FindString("1-2")
+	if match == nil {
+		t.Fatal("no match")
+	}
+	if match.Foo != 1 || match.Bar != 2 {
+		t.Errorf("wrong values, got: %+v", match)
+	}
+}
+
+func TestParticipation(t *testing.T) {
+	type s struct {
+		Foo string `regexps:"foo"`
+		Bar string `regexps:"bar"`
+	}
+
+	defaults := map[string]string{"foo": "default", "bar": "default"}
+	pattern := MustCompileWithDefaults[s](`(?P<foo>\w*)(?:-(?P<bar>\w*))?`, defaults)
+
+	tests := []struct {
+		input    string
+		foo, bar string
+		took     map[string]bool
+	}{
+		{"a-b", "a", "b", map[string]bool{"foo": true, "bar": true}},
+		{"a", "a", "default", map[string]bool{"foo": true, "bar": false}},
+		{"-", "", "", map[string]bool{"foo": true, "bar": true}},
+	}
+
+	for _, test := range tests {
+		match, err := pattern.ParseString(test.input)
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+			continue
+		}
+		if match.Foo != test.foo || match.Bar != test.bar {
+			t.Errorf("%q: expected %q and %q, got %+v", test.input, test.foo, test.bar, match)
+		}
+		if took := pattern.Participated(test.input); !reflect.DeepEqual(took, test.took) {
+			t.Errorf("%q: expected participation %v, got %v", test.input, test.took, took)
+		}
+	}
+
+	type exists struct {
+		Foo string `regexps:"foo,exists"`
+	}
+	if _, err := CompileWithDefaults[exists](`(?P<bar>\w*)`, map[string]string{"foo": "x"}); err == nil {
+		t.Error("expected exists field without a group to fail to compile")
+	}
+	if _, err := Compile[exists](`(?P<foo>\w*)`); err != nil {
+		t.Errorf("compile: %v", err)
+	}
+}
+
+func TestTime(t *testing.T) {
+	type s struct {
+		Date  time.Time `regexps:"date,required,layout=2006.01.02"`
+		Other time.Time `regexps:"other"`
+	}
+
+	pattern := MustCompile[s](`(?P<date>[\d.]+) (?P<other>[\d-]+)`)
+
+	match := pattern.FindString("2023.10.05 2022-01-02")
+	if match == nil {
+		t.Fatal("no match")
+	}
+	if !match.Date.Equal(time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("wrong date, got: %v", match.Date)
+	}
+	if !match.Other.Equal(time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("wrong date, got: %v", match.Other)
+	}
+
+	if pattern.FindString("2023.13.05 2022-01-02") != nil {
+		t.Error("matched invalid date")
+	}
+}
+
+func TestDuplicateGroups(t *testing.T) {
+	type s struct {
+		Foo int `regexps:"foo,required"`
+	}
+
+	pattern := MustCompile[s](`a(?P<foo>\d+)|b(?P<foo>\d+)`)
+
+	for target, expected := range map[string]int{"a1": 1, "b2": 2} {
+		match := pattern.FindString(target)
+		if match == nil {
+			t.Errorf("no match for %q", target)
+		} else if match.Foo != expected {
+			t.Errorf("wrong value for %q, got: %d", target, match.Foo)
+		}
+	}
+}
+
+func TestGroups(t *testing.T) {
+	type s struct {
+		Foo int `regexps:"foo,required"`
+	}
+
+	pattern := MustCompile[s](`(?P<foo>\w+)-(?P<bar>\w*)`)
+
+	groups := pattern.Groups("1a-")
+	if groups == nil || groups["foo"] != "1a" || groups["bar"] != "" {
+		t.Errorf("unexpected groups %v", groups)
+	}
+	if pattern.Groups("nothing") != nil {
+		t.Error("expected no groups without a match")
+	}
+
+	match, groups, err := pattern.ParseStringGroups("1a-", nil)
+	if match != nil || err == nil || groups["foo"] != "1a" {
+		t.Errorf("ParseStringGroups: expected the groups with the parse error, got %v, %v, %v", match, groups, err)
+	}
+	match, groups, err = pattern.ParseStringGroups("12-x", nil)
+	if err != nil || match.Foo != 12 || groups["bar"] != "x" {
+		t.Errorf("ParseStringGroups: unexpected %v, %v, %v", match, groups, err)
+	}
+}
+
+func TestParseString(t *testing.T) {
+	type s struct {
+		Name   string `regexps:"name,required"`
+		Season int    `regexps:"season,required"`
+	}
+
+	pattern := MustCompile[s](`(?P<name>\w*)\.S(?P<season>\w+)`)
+
+	match, err := pattern.ParseString("Show.S01")
+	if err != nil || match.Name != "Show" || match.Season != 1 {
+		t.Errorf("unexpected match %+v, %v", match, err)
+	}
+
+	_, err = pattern.ParseString("nothing")
+	if _, ok := err.(*NoMatchFoundError); !ok {
+		t.Errorf("expected no match, got %v", err)
+	}
+
+	_, err = pattern.ParseString("Show.S1a")
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Group() != "season" {
+		t.Errorf("expected a parse error for season, got %v", err)
+	}
+
+	_, err = pattern.ParseString(".S01")
+	if emptyErr, ok := err.(*RequiredGroupIsEmpty); !ok || emptyErr.Group() != "name" {
+		t.Errorf("expected name to be required, got %v", err)
+	}
+
+	if pattern.FindString("Show.S1a") != nil {
+		t.Error("expected FindString to find nothing")
+	}
+}
+
+func TestNumerals(t *testing.T) {
+	type s struct {
+		Roman int  `regexps:"roman,roman"`
+		Words uint `regexps:"words,words"`
+		Plain int  `regexps:"plain"`
+	}
+
+	pattern := MustCompile[s](`(?P<roman>\w+) (?P<words>[\w-]+) ?(?P<plain>\w*)`)
+
+	tests := []struct {
+		input string
+		roman int
+		words uint
+		ok    bool
+	}{
+		{"IV Two", 4, 2, true},
+		{"xii twenty-one", 12, 21, true},
+		{"3 Third", 3, 3, true},
+		{"MCMXCIV ninety", 1994, 90, true},
+		{"IIII two", 0, 0, false},
+		{"IV lots", 0, 0, false},
+		{"IV two IV", 0, 0, false},
+	}
+
+	for _, test := range tests {
+		match, err := pattern.ParseString(test.input)
+		if !test.ok {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %+v", test.input, match)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+		} else if match.Roman != test.roman || match.Words != test.words {
+			t.Errorf("%q: expected %d and %d, got %+v", test.input, test.roman, test.words, match)
+		}
+	}
+}
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type shout string
+
+func TestCustomParsers(t *testing.T) {
+	RegisterParser(func(src string, opts []string) (shout, error) {
+		if slices.Contains(opts, "quiet") {
+			return shout(strings.ToLower(src)), nil
+		}
+		return shout(strings.ToUpper(src) + "!"), nil
+	})
+
+	type s struct {
+		Level level `regexps:"level,required"`
+		Loud  shout `regexps:"loud"`
+		Quiet shout `regexps:"quiet,quiet"`
+	}
+
+	pattern := MustCompile[s](`(?P<level>\w+) (?P<loud>\w+) (?P<quiet>\w+)`)
+
+	match, err := pattern.ParseString("high hey HO")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if match.Level != 2 || match.Loud != "HEY!" || match.Quiet != "ho" {
+		t.Errorf("unexpected match %+v", match)
+	}
+
+	if _, err := pattern.ParseString("medium hey ho"); err == nil {
+		t.Error("expected the unmarshaler's error")
+	}
+}
+
+func TestPointerFields(t *testing.T) {
+	type inner struct {
+		Width  int `regexps:"width"`
+		Height int `regexps:"height"`
+	}
+	type s struct {
+		Name  string  `regexps:"name,required"`
+		Year  *int    `regexps:"year"`
+		Title *string `regexps:"title"`
+		Size  *inner
+	}
+
+	pattern := MustCompile[s](`(?P<name>\w+)(?: \((?P<year>\d+)\))?(?: - (?P<title>\w+))?(?: (?P<width>\d+)x(?P<height>\d+))?`)
+
+	match, err := pattern.ParseString("Foo")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if match.Year != nil || match.Title != nil || match.Size != nil {
+		t.Errorf("expected nil optional fields, got %+v", match)
+	}
+
+	match, err = pattern.ParseString("Foo (2001) - Bar 640x480")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if match.Year == nil || *match.Year != 2001 {
+		t.Errorf("wrong year, got %v", match.Year)
+	}
+	if match.Title == nil || *match.Title != "Bar" {
+		t.Errorf("wrong title, got %v", match.Title)
+	}
+	if match.Size == nil || *match.Size != (inner{640, 480}) {
+		t.Errorf("wrong size, got %+v", match.Size)
+	}
+}
+
+func TestSliceFields(t *testing.T) {
+	type s struct {
+		Episodes []int    `regexps:"episode,required"`
+		Tags     []string `regexps:"tags,sep=+"`
+	}
+
+	pattern := MustCompile[s](`e(?P<episode>\d+)(?:-e(?P<episode>\d+))?(?: \[(?P<tags>[^\]]*)\])?`)
+
+	tests := []struct {
+		input    string
+		episodes []int
+		tags     []string
+	}{
+		{"e01", []int{1}, nil},
+		{"e01-e02 [hdr + atmos]", []int{1, 2}, []string{"hdr", "atmos"}},
+		{"e03 [+]", []int{3}, nil},
+	}
+
+	for _, test := range tests {
+		match, err := pattern.ParseString(test.input)
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+			continue
+		}
+		if !slices.Equal(match.Episodes, test.episodes) || !slices.Equal(match.Tags, test.tags) {
+			t.Errorf("%q: expected %v and %v, got %+v", test.input, test.episodes, test.tags, match)
+		}
+	}
+
+	type unparsable struct {
+		Values []chan int `regexps:"episode"`
+	}
+	if _, err := Compile[unparsable](`(?P<episode>\d+)`); err == nil {
+		t.Error("expected slice of unparsable type to fail to compile")
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	type s struct {
+		Season  int `regexps:"season,required"`
+		Episode int `regexps:"episode,required"`
+	}
+
+	pattern := MustCompile[s](`S(?P<season>\d+)E(?P<episode>\w+)`)
+	input := "S01E02, S01Exx and S02E03"
+
+	all := pattern.FindAllString(input)
+	if len(all) != 2 || *all[0] != (s{1, 2}) || *all[1] != (s{2, 3}) {
+		t.Errorf("FindAllString: unexpected matches %v", all)
+	}
+	if pattern.FindAllString("nothing") != nil {
+		t.Error("FindAllString: expected nil without matches")
+	}
+
+	indexed := pattern.FindAllStringIndex(input)
+	if len(indexed) != 2 {
+		t.Fatalf("FindAllStringIndex: expected 2 matches, got %d", len(indexed))
+	}
+	for i, expected := range []string{"S01E02", "S02E03"} {
+		if m := indexed[i]; input[m.Start:m.End] != expected {
+			t.Errorf("FindAllStringIndex: expected %q, got %q", expected, input[m.Start:m.End])
+		}
+	}
+
+	parsed := pattern.ParseAllString(input)
+	if len(parsed) != 3 {
+		t.Fatalf("ParseAllString: expected 3 matches, got %d", len(parsed))
+	}
+	var parseErr *ParseError
+	if parsed[1].Value != nil || !errors.As(parsed[1].Err, &parseErr) || parseErr.Group() != "episode" {
+		t.Errorf("ParseAllString: expected a parse error for the episode, got %+v", parsed[1])
+	}
+	if input[parsed[1].Start:parsed[1].End] != "S01Exx" {
+		t.Errorf("ParseAllString: wrong position %d-%d", parsed[1].Start, parsed[1].End)
+	}
+	if parsed[2].Err != nil || *parsed[2].Value != (s{2, 3}) {
+		t.Errorf("ParseAllString: unexpected match %+v", parsed[2])
+	}
+
+	var seen int
+	pattern.EachString(input, func(m Match[s]) bool {
+		seen++
+		return m.Err == nil
+	})
+	if seen != 2 {
+		t.Errorf("EachString: expected to stop after 2 matches, saw %d", seen)
+	}
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	type s struct {
+		Name    string `regexps:"name,required"`
+		Season  int    `regexps:"season,required,words"`
+		Episode int    `regexps:"episode,required"`
+		Label   string `regexps:"label"`
+	}
+
+	defaults := map[string]string{
+		"name":   "{{ .grandparent }}",
+		"season": "{{ replace `(?i)^season ` `` .parent }}",
+		"label":  "{{ .episode }} of {{ .missing }}{{ .part }}",
+		"part":   "x",
+	}
+	pattern := MustCompileWithDefaults[s](`^(?:(?P<name>\w+) )?(?P<episode>\d+)`, defaults)
+
+	tests := []struct {
+		input   string
+		context map[string]string
+		match   s
+	}{
+		{"01 - Title", map[string]string{"parent": "Season 3", "grandparent": "Show"}, s{"Show", 3, 1, "01 of x"}},
+		{"Other 02", map[string]string{"parent": "Season Two", "grandparent": "Show"}, s{"Other", 2, 2, "02 of x"}},
+	}
+	for _, test := range tests {
+		match, err := pattern.ParseStringContext(test.input, test.context)
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+		} else if *match != test.match {
+			t.Errorf("%q: expected %+v, got %+v", test.input, test.match, *match)
+		}
+	}
+
+	var empty *RequiredGroupIsEmpty
+	if _, err := pattern.ParseString("01"); !errors.As(err, &empty) {
+		t.Errorf("expected an empty required group without context, got %v", err)
+	}
+
+	if _, err := CompileWithDefaults[s](`(?P<episode>\d+)`, map[string]string{"name": "{{ .parent", "season": "1"}); err == nil {
+		t.Error("expected a bad default template to fail to compile")
+	}
+}
+
+func TestEachString(t *testing.T) {
+	type s struct {
+		Word string `regexps:"word"`
+	}
+	tests := []struct {
+		pattern string
+		input   string
+	}{
+		{`(?P<word>a*)`, "baaacaé"},
+		{`(?P<word>\w*)`, "ab  cd é"},
+		{`(?P<word>x?)`, ""},
+		{`^(?P<word>a)`, "aaa"},
+		{`\b(?P<word>\w+)`, "one two"},
+	}
+
+	for _, test := range tests {
+		pattern := MustCompile[s](test.pattern)
+		var got [][]int
+		pattern.EachString(test.input, func(m Match[s]) bool {
+			if m.Err != nil || m.Value.Word != test.input[m.Start:m.End] {
+				t.Errorf("%s: unexpected match %+v", test.pattern, m)
+			}
+			got = append(got, []int{m.Start, m.End})
+			return true
+		})
+		expected := regexp.MustCompile(test.pattern).FindAllStringIndex(test.input, -1)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s on %q: expected matches at %v, got %v", test.pattern, test.input, expected, got)
+		}
+	}
+}
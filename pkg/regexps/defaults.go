@@ -0,0 +1,131 @@
+/*
+   Copyright 2020 Ori Seri
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package regexps
+
+import (
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// TextFuncs returns the functions for working with text that default
+// templates share with other templates, such as output templates, so that
+// they behave the same in both: replace, trim, lower and upper.
+func TextFuncs() template.FuncMap {
+	return template.FuncMap{
+		"replace": replace,
+		"trim":    strings.TrimSpace,
+		"lower":   strings.ToLower,
+		"upper":   strings.ToUpper,
+	}
+}
+
+// defaultFuncs are the functions available in default templates, for
+// picking the value of a group out of another group or the context, besides
+// TextFuncs.
+var defaultFuncs = template.FuncMap{
+	"number": number,
+}
+
+// isDefaultTemplate reports whether the default value v is a template to be
+// evaluated for each match, rather than used as it is
+func isDefaultTemplate(v string) bool {
+	return strings.Contains(v, "{{")
+}
+
+// parseDefaults parses the defaults that are templates
+func parseDefaults(defaults map[string]string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+	for k, v := range defaults {
+		if !isDefaultTemplate(v) {
+			continue
+		}
+		tmpl, err := template.New(k).Funcs(TextFuncs()).Funcs(defaultFuncs).Option("missingkey=zero").Parse(v)
+		if err != nil {
+			return nil, &CompileError{err}
+		}
+		templates[k] = tmpl
+	}
+	return templates, nil
+}
+
+// matchValues are the values of the groups in a single match, and the defaults of
+// those that didn't participate in it.
+type matchValues struct {
+	groups   map[string][]submatch
+	defaults map[string]string
+}
+
+// newMatchValues evaluates the defaults for the match of s at loc. Templates are given
+// the context, overridden by the value of every group that participated in the match
+// and the plain defaults of those that didn't, but not the values of other templates.
+func (r *Regexp[T]) newMatchValues(s string, loc []int, context map[string]string) (*matchValues, error) {
+	m := &matchValues{groups: r.matchGroupMap(s, loc), defaults: r.defaults}
+	if len(r.templates) == 0 {
+		return m, nil
+	}
+
+	data := make(map[string]string)
+	for k, v := range context {
+		data[k] = v
+	}
+	for k, v := range r.defaults {
+		if !isDefaultTemplate(v) && !participated(m.groups[k]) {
+			data[k] = v
+		}
+	}
+	for k, v := range m.groups {
+		if participated(v) {
+			data[k] = firstValue(v)
+		}
+	}
+
+	m.defaults = make(map[string]string, len(r.defaults))
+	for k, v := range r.defaults {
+		m.defaults[k] = v
+	}
+	var b strings.Builder
+	for k, tmpl := range r.templates {
+		if participated(m.groups[k]) {
+			continue
+		}
+		b.Reset()
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, &ParseError{group: k, err: err}
+		}
+		m.defaults[k] = b.String()
+	}
+	return m, nil
+}
+
+var numberPattern = regexp.MustCompile(`\d+`)
+
+// number returns the first number written in digits in s, e.g. "03" for
+// "Season 03", or "" if there is none
+func number(s string) string {
+	return numberPattern.FindString(s)
+}
+
+// replace replaces the matches of the regular expression pattern in s with
+// replacement, which may refer to submatches as in regexp.ReplaceAllString.
+func replace(pattern, replacement, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(s, replacement), nil
+}
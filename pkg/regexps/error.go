@@ -66,6 +66,15 @@ func (p *ParseError) Error() string {
 	return fmt.Sprintf("error parsing group \"%s\": %v", p.group, p.err)
 }
 
+func (p *ParseError) Unwrap() error {
+	return p.err
+}
+
+// Group returns the name of the group that couldn't be parsed
+func (p *ParseError) Group() string {
+	return p.group
+}
+
 // RequiredGroupIsEmpty returned when a required group is empty in the re match
 type RequiredGroupIsEmpty struct {
 	groupName string
@@ -75,3 +84,8 @@ type RequiredGroupIsEmpty struct {
 func (r *RequiredGroupIsEmpty) Error() string {
 	return fmt.Sprintf("required regroup \"%s\" is empty for field \"%s\"", r.groupName, r.fieldName)
 }
+
+// Group returns the name of the empty group
+func (r *RequiredGroupIsEmpty) Group() string {
+	return r.groupName
+}
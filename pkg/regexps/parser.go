@@ -17,12 +17,19 @@
 package regexps
 
 import (
+	"encoding"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
-type parseFunc func(src string, typ reflect.Type) (reflect.Value, error)
+const (
+	romanOption = "roman"
+	wordsOption = "words"
+)
+
+type parseFunc func(src string, typ reflect.Type, opts []string) (reflect.Value, error)
 
 var builtinTypesParsingFuncs = map[reflect.Kind]parseFunc{
 	reflect.Bool:    parseBool,
@@ -43,41 +50,200 @@ var builtinTypesParsingFuncs = map[reflect.Kind]parseFunc{
 
 var typesParsingFuncs = map[reflect.Type]parseFunc{
 	reflect.TypeOf(time.Second): parseDuration,
+	reflect.TypeOf(time.Time{}): parseTime,
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// RegisterParser makes fields of type T parsable with parse, which is given
+// the text of the group and the options of the field's struct tag. It takes
+// precedence over the builtin parsing of T, and should be called before any
+// pattern using T is compiled, e.g. in an init function.
+func RegisterParser[T any](parse func(src string, opts []string) (T, error)) {
+	typesParsingFuncs[reflect.TypeOf((*T)(nil)).Elem()] = func(src string, _ reflect.Type, opts []string) (reflect.Value, error) {
+		v, err := parse(src, opts)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(v), nil
+	}
 }
 
+// getParsingFunc returns the parsing function for typ: a registered one,
+// then encoding.TextUnmarshaler, then the builtin one for its kind.
 func getParsingFunc(typ reflect.Type) parseFunc {
 	if parsingFunc, ok := typesParsingFuncs[typ]; ok {
 		return parsingFunc
 	}
+	if reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return parseTextUnmarshaler
+	}
 	if parsingFunc, ok := builtinTypesParsingFuncs[typ.Kind()]; ok {
 		return parsingFunc
 	}
 	return nil
 }
 
-func parseString(src string, typ reflect.Type) (reflect.Value, error) {
+func parseTextUnmarshaler(src string, typ reflect.Type, _ []string) (reflect.Value, error) {
+	v := reflect.New(typ)
+	if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(src)); err != nil {
+		return reflect.Value{}, err
+	}
+	return v.Elem(), nil
+}
+
+func parseString(src string, typ reflect.Type, _ []string) (reflect.Value, error) {
 	return reflect.ValueOf(src).Convert(typ), nil
 }
 
-func parseInt(src string, typ reflect.Type) (reflect.Value, error) {
+func parseInt(src string, typ reflect.Type, opts []string) (reflect.Value, error) {
 	n, err := strconv.ParseInt(src, 10, 64)
 	if err != nil {
-		return reflect.Value{}, err
+		u, ok := parseNumeral(src, opts)
+		if !ok {
+			return reflect.Value{}, err
+		}
+		n = int64(u)
 	}
 
 	return reflect.ValueOf(n).Convert(typ), nil
 }
 
-func parseUInt(src string, typ reflect.Type) (reflect.Value, error) {
+func parseUInt(src string, typ reflect.Type, opts []string) (reflect.Value, error) {
 	n, err := strconv.ParseUint(src, 10, 64)
 	if err != nil {
-		return reflect.Value{}, err
+		u, ok := parseNumeral(src, opts)
+		if !ok {
+			return reflect.Value{}, err
+		}
+		n = u
 	}
 
 	return reflect.ValueOf(n).Convert(typ), nil
 }
 
-func parseFloat(src string, typ reflect.Type) (reflect.Value, error) {
+// parseNumeral parses a number that isn't written in digits, as a roman
+// numeral or in words, if the field's options allow it
+func parseNumeral(src string, opts []string) (uint64, bool) {
+	for _, opt := range opts {
+		switch opt {
+		case romanOption:
+			if n, ok := parseRoman(src); ok {
+				return n, true
+			}
+		case wordsOption:
+			if n, ok := parseWords(src); ok {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+var romanValues = map[rune]uint64{
+	'I': 1,
+	'V': 5,
+	'X': 10,
+	'L': 50,
+	'C': 100,
+	'D': 500,
+	'M': 1000,
+}
+
+// parseRoman parses a roman numeral such as "IV" or "xii". Only numerals
+// in their canonical form are accepted, so "IIII" is not 4.
+func parseRoman(src string) (uint64, bool) {
+	src = strings.ToUpper(src)
+	var n uint64
+	for i, r := range src {
+		v, ok := romanValues[r]
+		if !ok {
+			return 0, false
+		}
+		if i+1 < len(src) && v < romanValues[rune(src[i+1])] {
+			n -= v
+		} else {
+			n += v
+		}
+	}
+	if n == 0 || n >= 4000 || formatRoman(n) != src {
+		return 0, false
+	}
+	return n, true
+}
+
+func formatRoman(n uint64) string {
+	numerals := []struct {
+		value  uint64
+		symbol string
+	}{
+		{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"},
+		{100, "C"}, {90, "XC"}, {50, "L"}, {40, "XL"},
+		{10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+	}
+	var b strings.Builder
+	for _, v := range numerals {
+		for n >= v.value {
+			b.WriteString(v.symbol)
+			n -= v.value
+		}
+	}
+	return b.String()
+}
+
+var unitWords = []string{
+	"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+	"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen",
+}
+
+var ordinalWords = []string{
+	"zeroth", "first", "second", "third", "fourth", "fifth", "sixth", "seventh", "eighth", "ninth",
+	"tenth", "eleventh", "twelfth", "thirteenth", "fourteenth", "fifteenth", "sixteenth", "seventeenth", "eighteenth", "nineteenth",
+}
+
+var tensWords = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+
+var tensOrdinalWords = []string{"", "", "twentieth", "thirtieth", "fortieth", "fiftieth", "sixtieth", "seventieth", "eightieth", "ninetieth"}
+
+// parseWords parses a number below 100 written in English words, either
+// cardinal or ordinal, such as "Two", "twenty-one" or "Third".
+func parseWords(src string) (uint64, bool) {
+	words := strings.FieldsFunc(strings.ToLower(src), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_' || r == '.'
+	})
+	switch len(words) {
+	case 1:
+		if n, ok := wordValue(words[0], unitWords, ordinalWords); ok {
+			return n, true
+		}
+		if n, ok := wordValue(words[0], tensWords, tensOrdinalWords); ok && n >= 2 {
+			return 10 * n, true
+		}
+	case 2:
+		tens, ok := wordValue(words[0], tensWords, nil)
+		if !ok || tens < 2 {
+			return 0, false
+		}
+		if units, ok := wordValue(words[1], unitWords, ordinalWords); ok && units >= 1 && units <= 9 {
+			return 10*tens + units, true
+		}
+	}
+	return 0, false
+}
+
+// wordValue returns the index of word in either list
+func wordValue(word string, lists ...[]string) (uint64, bool) {
+	for _, list := range lists {
+		for i, v := range list {
+			if v != "" && v == word {
+				return uint64(i), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func parseFloat(src string, typ reflect.Type, _ []string) (reflect.Value, error) {
 	n, err := strconv.ParseFloat(src, 64)
 	if err != nil {
 		return reflect.Value{}, err
@@ -85,7 +251,7 @@ func parseFloat(src string, typ reflect.Type) (reflect.Value, error) {
 	return reflect.ValueOf(n).Convert(typ), nil
 }
 
-func parseBool(src string, _ reflect.Type) (reflect.Value, error) {
+func parseBool(src string, _ reflect.Type, _ []string) (reflect.Value, error) {
 	b, err := strconv.ParseBool(src)
 	if err != nil {
 		return reflect.Value{}, err
@@ -93,10 +259,25 @@ func parseBool(src string, _ reflect.Type) (reflect.Value, error) {
 	return reflect.ValueOf(b), nil
 }
 
-func parseDuration(src string, _ reflect.Type) (reflect.Value, error) {
+func parseDuration(src string, _ reflect.Type, _ []string) (reflect.Value, error) {
 	d, err := time.ParseDuration(src)
 	if err != nil {
 		return reflect.Value{}, err
 	}
 	return reflect.ValueOf(d), nil
 }
+
+// defaultTimeLayout is used for time.Time fields without a layout option
+const defaultTimeLayout = "2006-01-02"
+
+func parseTime(src string, _ reflect.Type, opts []string) (reflect.Value, error) {
+	layout, ok := optionValue(opts, layoutOption)
+	if !ok {
+		layout = defaultTimeLayout
+	}
+	t, err := time.Parse(layout, src)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.ValueOf(t), nil
+}
@@ -27,19 +27,55 @@ import (
 const (
 	requiredOption = "required"
 	existsOption   = "exists"
+	layoutOption   = "layout"
+	sepOption      = "sep"
 )
 
-// matchGroupMap converts the match string array into a map of group keys to group values
-func (r *Regexp[T]) matchGroupMap(match []string) map[string]string {
-	ret := make(map[string]string)
+// submatch is the text of a capture group, and whether the group participated in the match.
+// A group that participated may still be empty, e.g. `(?P<foo>\d*)`.
+type submatch struct {
+	value        string
+	participated bool
+}
+
+// matchGroupMap converts the submatch indices of a match in s into a map of group keys to the
+// submatches of every group with that key, in the order they appear in the pattern. Several
+// groups can share a name, such as in different branches of an alternation.
+func (r *Regexp[T]) matchGroupMap(s string, loc []int) map[string][]submatch {
+	ret := make(map[string][]submatch)
 	for i, name := range r.matcher.SubexpNames() {
-		if i != 0 && name != "" {
-			ret[name] = match[i]
+		if i == 0 || name == "" {
+			continue
+		}
+		var m submatch
+		if start, end := loc[2*i], loc[2*i+1]; start >= 0 {
+			m = submatch{value: s[start:end], participated: true}
 		}
+		ret[name] = append(ret[name], m)
 	}
 	return ret
 }
 
+// firstValue returns the first of values that matched something
+func firstValue(values []submatch) string {
+	for _, v := range values {
+		if v.value != "" {
+			return v.value
+		}
+	}
+	return ""
+}
+
+// participated reports whether any of values participated in the match
+func participated(values []submatch) bool {
+	for _, v := range values {
+		if v.participated {
+			return true
+		}
+	}
+	return false
+}
+
 // groupAndOption returns the requested regexps and its options split by ','
 func groupAndOption(fieldType reflect.StructField) (group string, option []string) {
 	regroupKey := fieldType.Tag.Get("regexps")
@@ -57,6 +93,16 @@ func groupAndOption(fieldType reflect.StructField) (group string, option []strin
 	return strings.TrimSpace(split[0]), options
 }
 
+// optionValue returns the value of a "name=value" option
+func optionValue(opts []string, name string) (string, bool) {
+	for _, opt := range opts {
+		if k, v, ok := strings.Cut(opt, "="); ok && strings.TrimSpace(k) == name {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return "", false
+}
+
 // validateStruct checks that all fields in the given struct are valid
 func (r *Regexp[T]) validateStruct(targetRef reflect.Value) error {
 	targetType := targetRef.Type()
@@ -72,20 +118,16 @@ func (r *Regexp[T]) validateStruct(targetRef reflect.Value) error {
 	return nil
 }
 
-// validateField ensures that the given field is present if required, or (TODO) has a default value
+// validateField ensures that the given field is present if required, and can be parsed
 func (r *Regexp[T]) validateField(fieldType reflect.StructField, fieldRef reflect.Value) error {
 	fieldRefType := fieldType.Type
-	ptr := false
 	if fieldRefType.Kind() == reflect.Ptr {
-		ptr = true
 		fieldRefType = fieldType.Type.Elem()
 	}
-	if fieldRefType.Kind() == reflect.Struct {
-		if ptr {
-			if fieldRef.IsNil() {
-				return fmt.Errorf("can't set value to nil pointer in struct field: %s", fieldType.Name)
-			}
-			fieldRef = fieldRef.Elem()
+	if fieldRefType.Kind() == reflect.Struct && getParsingFunc(fieldRefType) == nil {
+		// Nil struct pointers are allocated when matching, so check a new one
+		if fieldType.Type.Kind() == reflect.Ptr {
+			return r.validateStruct(reflect.New(fieldRefType).Elem())
 		}
 		return r.validateStruct(fieldRef)
 	}
@@ -93,6 +135,12 @@ func (r *Regexp[T]) validateField(fieldType reflect.StructField, fieldRef reflec
 	if key == "" {
 		return nil
 	}
+	if isSliceField(fieldRefType) {
+		fieldRefType = fieldRefType.Elem()
+	}
+	if getParsingFunc(fieldRefType) == nil {
+		return &TypeNotParsableError{fieldRefType}
+	}
 	if slices.Contains(opts, requiredOption) {
 		if !slices.Contains(r.matcher.SubexpNames(), key) {
 			if _, ok := r.defaults[key]; !ok {
@@ -100,52 +148,144 @@ func (r *Regexp[T]) validateField(fieldType reflect.StructField, fieldRef reflec
 			}
 		}
 	}
+	// Unlike required, exists is only satisfied by the group itself, not a default
+	if slices.Contains(opts, existsOption) && !slices.Contains(r.matcher.SubexpNames(), key) {
+		return &UnknownGroupError{group: key}
+	}
 	return nil
 }
 
+// isSliceField reports whether fields of typ are filled with every value of their group,
+// rather than parsed as a whole
+func isSliceField(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice && getParsingFunc(typ) == nil
+}
+
 // setField getting a single struct field and matching groups map and set the field value to its matching group value tag
-// after parsing it to match the field type
-func (r *Regexp[T]) setField(fieldType reflect.StructField, fieldRef reflect.Value, matchGroup map[string]string) error {
+// after parsing it to match the field type. Defaults are used only for groups that didn't participate in the match.
+// Pointer fields are left nil if their group matched nothing, and nested struct pointers are allocated if any of
+// their fields are set.
+func (r *Regexp[T]) setField(fieldType reflect.StructField, fieldRef reflect.Value, match *matchValues) error {
 	fieldRefType := fieldType.Type
 	ptr := false
 	if fieldRefType.Kind() == reflect.Ptr {
+		ptr = true
 		fieldRefType = fieldType.Type.Elem()
 	}
 
-	if fieldRefType.Kind() == reflect.Struct {
-		return r.fillTarget(matchGroup, fieldRef)
+	if fieldRefType.Kind() == reflect.Struct && getParsingFunc(fieldRefType) == nil {
+		if !ptr {
+			return r.fillTarget(match, fieldRef)
+		}
+		nested := reflect.New(fieldRefType)
+		if err := r.fillTarget(match, nested.Elem()); err != nil {
+			return err
+		}
+		if nested.Elem().IsZero() {
+			nested = reflect.Zero(fieldType.Type)
+		}
+		fieldRef.Set(nested)
+		return nil
 	}
 
-	key, _ := groupAndOption(fieldType)
+	key, opts := groupAndOption(fieldType)
 	if key == "" {
 		return nil
 	}
 
-	if ptr {
-		fieldRef = fieldRef.Elem()
+	if !ptr && isSliceField(fieldRefType) {
+		return r.setSlice(fieldType, fieldRef, key, opts, match)
 	}
 
-	matchedVal, ok := matchGroup[key]
-	if !ok {
-		matchedVal = r.defaults[key]
+	matchedVal := firstValue(match.groups[key])
+	if !participated(match.groups[key]) {
+		matchedVal = match.defaults[key]
 	}
 
-	parsedFunc := getParsingFunc(fieldRefType)
-	if parsedFunc == nil {
-		return &TypeNotParsableError{fieldRefType}
+	// Optional groups that matched nothing leave the field at its zero value
+	if matchedVal == "" {
+		if slices.Contains(opts, requiredOption) {
+			return &RequiredGroupIsEmpty{groupName: key, fieldName: fieldType.Name}
+		}
+		fieldRef.Set(reflect.Zero(fieldType.Type))
+		return nil
 	}
 
-	parsed, err := parsedFunc(matchedVal, fieldRefType)
+	parsed, err := r.parseValue(key, matchedVal, fieldRefType, opts)
 	if err != nil {
-		return &ParseError{group: key, err: err}
+		return err
 	}
 
+	if ptr {
+		value := reflect.New(fieldRefType)
+		value.Elem().Set(parsed)
+		parsed = value
+	}
 	fieldRef.Set(parsed)
 
 	return nil
 }
 
-func (r *Regexp[T]) fillTarget(matchGroup map[string]string, targetRef reflect.Value) error {
+// setSlice sets a slice field to the values of every group named key that matched
+// something, each split by the field's sep option if it has one
+func (r *Regexp[T]) setSlice(fieldType reflect.StructField, fieldRef reflect.Value, key string, opts []string, match *matchValues) error {
+	var matched []string
+	for _, v := range match.groups[key] {
+		if v.value != "" {
+			matched = append(matched, v.value)
+		}
+	}
+	if !participated(match.groups[key]) && match.defaults[key] != "" {
+		matched = []string{match.defaults[key]}
+	}
+
+	if sep, ok := optionValue(opts, sepOption); ok && sep != "" {
+		var split []string
+		for _, v := range matched {
+			for _, part := range strings.Split(v, sep) {
+				if part = strings.TrimSpace(part); part != "" {
+					split = append(split, part)
+				}
+			}
+		}
+		matched = split
+	}
+
+	if len(matched) == 0 {
+		if slices.Contains(opts, requiredOption) {
+			return &RequiredGroupIsEmpty{groupName: key, fieldName: fieldType.Name}
+		}
+		fieldRef.Set(reflect.Zero(fieldType.Type))
+		return nil
+	}
+
+	slice := reflect.MakeSlice(fieldType.Type, len(matched), len(matched))
+	for i, v := range matched {
+		parsed, err := r.parseValue(key, v, fieldType.Type.Elem(), opts)
+		if err != nil {
+			return err
+		}
+		slice.Index(i).Set(parsed)
+	}
+	fieldRef.Set(slice)
+	return nil
+}
+
+// parseValue parses the value of the group key as typ
+func (r *Regexp[T]) parseValue(key, value string, typ reflect.Type, opts []string) (reflect.Value, error) {
+	parsedFunc := getParsingFunc(typ)
+	if parsedFunc == nil {
+		return reflect.Value{}, &TypeNotParsableError{typ}
+	}
+
+	parsed, err := parsedFunc(value, typ, opts)
+	if err != nil {
+		return reflect.Value{}, &ParseError{group: key, err: err}
+	}
+	return parsed, nil
+}
+
+func (r *Regexp[T]) fillTarget(match *matchValues, targetRef reflect.Value) error {
 	targetType := targetRef.Type()
 	for i := 0; i < targetType.NumField(); i++ {
 		fieldRef := targetRef.Field(i)
@@ -153,7 +293,7 @@ func (r *Regexp[T]) fillTarget(matchGroup map[string]string, targetRef reflect.V
 			continue
 		}
 
-		if err := r.setField(targetType.Field(i), fieldRef, matchGroup); err != nil {
+		if err := r.setField(targetType.Field(i), fieldRef, match); err != nil {
 			return err
 		}
 	}